@@ -3,36 +3,81 @@ package agent
 import (
 	"context"
 	"fmt"
+	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
+	"github.com/codementor/codementor/internal/checkpoint"
 	"github.com/codementor/codementor/internal/config"
+	"github.com/codementor/codementor/internal/embedcache"
 	"github.com/codementor/codementor/internal/indexer"
 	"github.com/codementor/codementor/internal/llm"
+	"github.com/codementor/codementor/internal/pipeline"
 	"github.com/codementor/codementor/internal/retriever"
 )
 
+// checkpointBatchSize controls how many chunks are embedded between
+// checkpoint commits during indexing.
+const checkpointBatchSize = 20
+
+// graphExpandBudget caps how many 1-hop reference-graph neighbors Ask and
+// AskStream pull into a result set beyond the raw top-K hits.
+const graphExpandBudget = 5
+
 // RAGAgent is the main agent that orchestrates RAG-based code Q&A
 type RAGAgent struct {
-	config      *config.Config
-	llmClient   *llm.Client
-	vectorStore retriever.VectorStore
-	retriever   *retriever.HybridRetriever
-	indexer     *indexer.Indexer
-	history     []llm.Message
-	repoName    string
-	qdrantStore *retriever.QdrantStore // Keep reference for HasData check
+	config         *config.Config
+	llmClient      llm.Provider
+	vectorStore    retriever.VectorStore
+	retriever      *retriever.HybridRetriever
+	indexer        *indexer.Indexer
+	history        []llm.Message
+	repoName       string
+	qdrantStore    *retriever.QdrantStore // Keep reference for HasData check
+	boltStore      *retriever.BoltStore   // Keep reference for per-file content-hash skip checks
+	objStore       *retriever.ObjStore    // Keep reference for shared-bucket BM25 rebuild check
+	redisStore     *retriever.RedisStore  // Keep reference for shared-instance BM25 rebuild check
+	codebertClient *llm.CodeBERTClient    // Set when Embedding.Provider == "codebert", used by IndexRepositoryStreaming
+	embedCache     *embedcache.Cache      // Content-addressed embedding cache shared by retriever (Index/TryUpdateChunks) and IndexRepositoryStreaming's Pipeline
 }
 
 // NewRAGAgent creates a new RAG agent
 func NewRAGAgent(cfg *config.Config) *RAGAgent {
-	llmClient := llm.NewClient(cfg.Ollama)
+	llmClient := llm.NewProvider(cfg.Ollama)
 
 	var store retriever.VectorStore
 	var qdrantStore *retriever.QdrantStore
+	var boltStore *retriever.BoltStore
+	var objStore *retriever.ObjStore
+	var redisStore *retriever.RedisStore
 
 	// Choose vector store based on config
 	switch cfg.Vector.Type {
+	case "bolt":
+		dataPath := fmt.Sprintf(".codementor/vectors_%s.bolt", cfg.Vector.Collection)
+		bs, err := retriever.NewBoltStore(dataPath, cfg.Vector.Dimension, cfg.Embedding.Provider)
+		if err != nil {
+			fmt.Printf("⚠️  Failed to open bolt store: %v\n", err)
+			fmt.Println("   Falling back to memory store")
+			legacyPath := fmt.Sprintf(".codementor/vectors_%s.json", cfg.Vector.Collection)
+			store = retriever.NewMemoryStoreWithWAL(legacyPath, cfg.Vector.WALSegmentBytes)
+		} else {
+			boltStore = bs
+			store = bs
+		}
+	case "objstore":
+		var client retriever.ObjectClient
+		switch cfg.Vector.ObjectProvider {
+		case "gcs":
+			client = retriever.NewGCSClient(cfg.Vector.Endpoint, cfg.Vector.Bucket, cfg.Vector.AccessKey, cfg.Vector.SecretKey)
+		case "swift":
+			client = retriever.NewSwiftClient(cfg.Vector.Endpoint, cfg.Vector.Bucket, cfg.Vector.AccessKey, cfg.Vector.SecretKey)
+		default:
+			client = retriever.NewS3Client(cfg.Vector.Endpoint, cfg.Vector.Bucket, cfg.Vector.Region, cfg.Vector.AccessKey, cfg.Vector.SecretKey)
+		}
+		objStore = retriever.NewObjStore(client, cfg.Vector.Prefix, cfg.Vector.Collection)
+		store = objStore
 	case "qdrant":
 		qdrantHost := fmt.Sprintf("http://%s:%d", cfg.Vector.Host, cfg.Vector.Port)
 		var err error
@@ -41,26 +86,70 @@ func NewRAGAgent(cfg *config.Config) *RAGAgent {
 			fmt.Printf("⚠️  Failed to connect to Qdrant: %v\n", err)
 			fmt.Println("   Falling back to memory store")
 			dataPath := fmt.Sprintf(".codementor/vectors_%s.json", cfg.Vector.Collection)
-			store = retriever.NewMemoryStore(dataPath)
+			store = retriever.NewMemoryStoreWithWAL(dataPath, cfg.Vector.WALSegmentBytes)
 		} else {
 			store = qdrantStore
 		}
+	case "redis":
+		var err error
+		redisStore, err = retriever.NewRedisStore(cfg.Vector.Host, cfg.Vector.Port, cfg.Vector.Password, cfg.Vector.DB, cfg.Vector.Collection, cfg.Vector.Dimension)
+		if err != nil {
+			fmt.Printf("⚠️  Failed to connect to Redis: %v\n", err)
+			fmt.Println("   Falling back to memory store")
+			dataPath := fmt.Sprintf(".codementor/vectors_%s.json", cfg.Vector.Collection)
+			store = retriever.NewMemoryStoreWithWAL(dataPath, cfg.Vector.WALSegmentBytes)
+		} else {
+			store = redisStore
+		}
 	default:
 		// Default to memory store
 		dataPath := fmt.Sprintf(".codementor/vectors_%s.json", cfg.Vector.Collection)
-		store = retriever.NewMemoryStore(dataPath)
+		store = retriever.NewMemoryStoreWithWAL(dataPath, cfg.Vector.WALSegmentBytes)
 	}
 
 	hybridRetriever := retriever.NewHybridRetriever(store, llmClient)
+	hybridRetriever.SetBM25Dir(fmt.Sprintf(".codementor/bm25_%s", cfg.Vector.Collection))
+	hybridRetriever.SetCollection(cfg.Vector.Collection)
+	hybridRetriever.ApplyTunedWeights()
+	hybridRetriever.SetReranker(retriever.NewLLMReranker(llmClient))
+	if graph, err := indexer.LoadGraph(); err == nil {
+		hybridRetriever.SetGraph(graph)
+	}
+
+	var codebertClient *llm.CodeBERTClient
+	if cfg.Embedding.Provider == "codebert" {
+		codebertClient = llm.NewCodeBERTClient(cfg.Embedding.Host)
+	}
+
+	// embedCache is shared by HybridRetriever.Index/TryUpdateChunks (the
+	// default IndexRepository path, any embedding.Provider) and
+	// IndexRepositoryStreaming's Pipeline (see SetCache below) — keyed by
+	// content hash and the active embedding model, so re-indexing a repo
+	// where only a few files changed skips re-embedding everything else on
+	// either path, not just the codebert/streaming one.
+	var embedCache *embedcache.Cache
+	cachePath := fmt.Sprintf(".codementor/embedcache_%s.jsonl", cfg.Vector.Collection)
+	cache, err := embedcache.New(cachePath)
+	if err != nil {
+		fmt.Printf("⚠️  Failed to open embedding cache: %v\n", err)
+	} else {
+		embedCache = cache
+		hybridRetriever.SetEmbedCache(cache, llmClient.GetEmbeddingModel())
+	}
 
 	return &RAGAgent{
-		config:      cfg,
-		llmClient:   llmClient,
-		vectorStore: store,
-		retriever:   hybridRetriever,
-		indexer:     indexer.NewIndexer(cfg.Indexer),
-		history:     []llm.Message{},
-		qdrantStore: qdrantStore,
+		config:         cfg,
+		llmClient:      llmClient,
+		vectorStore:    store,
+		retriever:      hybridRetriever,
+		indexer:        indexer.NewIndexer(cfg.Indexer),
+		history:        []llm.Message{},
+		qdrantStore:    qdrantStore,
+		boltStore:      boltStore,
+		objStore:       objStore,
+		redisStore:     redisStore,
+		codebertClient: codebertClient,
+		embedCache:     embedCache,
 	}
 }
 
@@ -75,14 +164,21 @@ func (a *RAGAgent) IndexRepository(ctx context.Context, repoPath string, progres
 	if existingCount > 0 {
 		fmt.Printf("✅ Found existing index with %d chunks (skipping re-indexing)\n", existingCount)
 
-		// Still need to build BM25 index from existing data
-		// For Qdrant, we need to load chunks for BM25
-		if a.qdrantStore != nil {
+		// Still need to build BM25 index from existing data. For a remote or
+		// shared store (Qdrant, an ObjStore pointed at an already-indexed
+		// bucket/prefix, or a Redis instance another replica already
+		// populated), the local BM25/graph state might not exist yet. The
+		// same is true locally the first time a repo indexed before BM25
+		// moved to a per-collection directory is opened with this build:
+		// HasBM25Data catches that case too instead of assuming a local
+		// store always already has it on disk.
+		if a.qdrantStore != nil || a.objStore != nil || a.redisStore != nil || !a.retriever.HasBM25Data() {
 			fmt.Println("   Loading chunks for keyword search...")
 			// Parse repo to get chunks for BM25 (fast, no embedding needed)
 			result, err := a.indexer.IndexRepository(repoPath)
 			if err == nil {
 				a.retriever.BuildBM25Index(result.Chunks)
+				a.rebuildGraph(result.Chunks)
 			}
 		}
 		return nil
@@ -98,7 +194,14 @@ func (a *RAGAgent) IndexRepository(ctx context.Context, repoPath string, progres
 		return fmt.Errorf("failed to parse repository: %w", err)
 	}
 
-	// Stage 2: Generate embeddings and index
+	// Stage 2: Generate embeddings and index, resuming from a checkpoint if
+	// a previous run was interrupted mid-embed.
+	cp, err := checkpoint.Load(a.config.Vector.Collection)
+	if err != nil {
+		return fmt.Errorf("failed to load checkpoint: %w", err)
+	}
+	a.retriever.SetCheckpoint(cp, checkpointBatchSize)
+
 	if progressFn != nil {
 		progressFn("embedding", 0, len(result.Chunks))
 	}
@@ -113,13 +216,281 @@ func (a *RAGAgent) IndexRepository(ctx context.Context, repoPath string, progres
 		return fmt.Errorf("failed to index: %w", err)
 	}
 
+	a.rebuildGraph(result.Chunks)
+
+	return recordFileStates(cp, absPath, result)
+}
+
+// IndexRepositoryStreaming indexes a repository through the bounded
+// parse/batch/embed pipeline instead of parsing the whole repo up front.
+// It requires the CodeBERT embedding provider (cfg.Embedding.Provider ==
+// "codebert") since the pipeline talks to CodeBERTClient.EmbedBatch
+// directly; onStats, if non-nil, is called after every per-stage counter
+// update.
+func (a *RAGAgent) IndexRepositoryStreaming(ctx context.Context, repoPath string, cfg pipeline.Config, onStats func(pipeline.Stats)) error {
+	if a.codebertClient == nil {
+		return fmt.Errorf("streaming indexing requires embedding.provider=codebert")
+	}
+
+	absPath, err := filepath.Abs(repoPath)
+	if err != nil {
+		return fmt.Errorf("failed to resolve path: %w", err)
+	}
+	a.repoName = filepath.Base(absPath)
+
+	files, err := a.indexer.Scan(absPath)
+	if err != nil {
+		return fmt.Errorf("failed to scan repository: %w", err)
+	}
+
+	p := pipeline.New(cfg, a.codebertClient, a.vectorStore, onStats)
+	if a.embedCache != nil {
+		health, err := a.codebertClient.CheckHealth(ctx)
+		if err == nil {
+			p.SetCache(a.embedCache, health.Model)
+		}
+	}
+	if err := p.Run(ctx, files, a.indexer.ParseFile); err != nil {
+		return fmt.Errorf("pipeline run failed: %w", err)
+	}
+
+	// BM25 still needs the full chunk set; re-parse is cheap relative to
+	// embedding and keeps ChunkFile/ParseFile as the single source of truth.
+	result, err := a.indexer.IndexRepository(repoPath)
+	if err != nil {
+		return fmt.Errorf("failed to build keyword index: %w", err)
+	}
+	a.retriever.BuildBM25Index(result.Chunks)
+	a.rebuildGraph(result.Chunks)
+
 	return nil
 }
 
+// IndexResume incrementally re-indexes a repository: it compares the
+// current file tree against the last checkpoint's file/mtime list and only
+// re-parses and re-embeds files that were added or modified, invalidating
+// the chunks belonging to deleted or modified files first.
+func (a *RAGAgent) IndexResume(ctx context.Context, repoPath string) error {
+	absPath, err := filepath.Abs(repoPath)
+	if err != nil {
+		return fmt.Errorf("failed to resolve path: %w", err)
+	}
+	a.repoName = filepath.Base(absPath)
+
+	cp, err := checkpoint.Load(a.config.Vector.Collection)
+	if err != nil {
+		return fmt.Errorf("failed to load checkpoint: %w", err)
+	}
+
+	files, err := a.indexer.Scan(absPath)
+	if err != nil {
+		return fmt.Errorf("failed to scan repository: %w", err)
+	}
+
+	prevFiles := cp.FileMap()
+	seen := make(map[string]bool, len(files))
+
+	var mtimeChanged []*indexer.FileInfo
+
+	for _, f := range files {
+		seen[f.RelPath] = true
+
+		info, statErr := os.Stat(f.Path)
+		if statErr != nil {
+			continue
+		}
+		mtime := info.ModTime().UnixNano()
+
+		if _, existed := prevFiles[f.RelPath]; !existed || prevFiles[f.RelPath].ModTime != mtime {
+			mtimeChanged = append(mtimeChanged, f)
+		}
+	}
+
+	var staleIDs []string
+	var changedChunks []*indexer.CodeChunk
+
+	// A newer mtime doesn't always mean different content (a touch, a
+	// re-save with no edits, a checkout that resets timestamps). When
+	// boltStore is in use we can tell the two apart cheaply: re-parsing is
+	// far cheaper than re-embedding, so parse first and only invalidate and
+	// re-embed the file if its chunks' combined content hash actually moved
+	// from what BoltStore last recorded.
+	for _, f := range mtimeChanged {
+		chunks, parseErr := a.indexer.ParseFile(f)
+		if parseErr != nil {
+			continue
+		}
+
+		if a.boltStore != nil {
+			hashes := make([]string, len(chunks))
+			for i, c := range chunks {
+				hashes[i] = c.ContentHash
+			}
+			if stored, ok := a.boltStore.FileHash(f.RelPath); ok && stored == retriever.CombinedChunkHash(hashes) {
+				continue
+			}
+		}
+
+		// Chunk IDs are derived from FilePath/ChunkType/Name/StartLine (see
+		// indexer.generateChunkID), not content, so an edit that doesn't
+		// shift a chunk's start line keeps its ID and only changes its
+		// ContentHash. Only IDs that genuinely disappeared from the file
+		// (a deleted function, or one whose start line moved) need
+		// invalidating; everything else goes through TryUpdateChunks below,
+		// which itself skips re-embedding unless ContentHash actually moved.
+		if prev, existed := prevFiles[f.RelPath]; existed {
+			stillPresent := make(map[string]bool, len(chunks))
+			for _, c := range chunks {
+				stillPresent[c.ID] = true
+			}
+			for _, id := range prev.ChunkIDs {
+				if !stillPresent[id] {
+					staleIDs = append(staleIDs, id)
+				}
+			}
+		}
+		changedChunks = append(changedChunks, chunks...)
+	}
+
+	// Anything recorded previously but no longer on disk was deleted.
+	for path, prev := range prevFiles {
+		if !seen[path] {
+			staleIDs = append(staleIDs, prev.ChunkIDs...)
+		}
+	}
+
+	if len(staleIDs) > 0 {
+		if err := a.vectorStore.Delete(staleIDs); err != nil {
+			return fmt.Errorf("failed to invalidate stale chunks: %w", err)
+		}
+	}
+
+	// TryUpdateChunks compare-and-swaps each chunk individually instead of a
+	// blanket delete+re-embed, so it naturally resumes after an interruption
+	// (a chunk already written matches on ContentHash and is skipped) and
+	// tolerates another IndexResume/handleIndex call racing against the same
+	// store without either one clobbering the other's write.
+	if len(changedChunks) > 0 {
+		if err := a.retriever.TryUpdateChunks(ctx, changedChunks); err != nil {
+			return fmt.Errorf("failed to update changed chunks: %w", err)
+		}
+	}
+
+	// Rebuild BM25 over the full current chunk set (cheap, no embedding).
+	result, err := a.indexer.IndexRepository(repoPath)
+	if err != nil {
+		return fmt.Errorf("failed to re-parse repository for keyword search: %w", err)
+	}
+	a.retriever.BuildBM25Index(result.Chunks)
+	a.rebuildGraph(result.Chunks)
+
+	return recordFileStates(cp, absPath, result)
+}
+
+// Watch starts a long-running filesystem watch on repoPath: instead of a
+// one-shot index, it re-parses and re-indexes each file as it's saved,
+// keeping the BM25 and vector indexes live for something like a code-search
+// sidecar. It blocks until ctx is cancelled. The reference graph is not
+// rebuilt here: BuildGraph needs the whole chunk set to resolve edges, and
+// doing that on every keystroke-triggered save would make watch mode
+// rebuild the entire graph per file instead of per batch, so a watched repo
+// keeps serving the graph from its last IndexRepository/IndexResume run.
+func (a *RAGAgent) Watch(ctx context.Context, repoPath string) error {
+	absPath, err := filepath.Abs(repoPath)
+	if err != nil {
+		return fmt.Errorf("failed to resolve path: %w", err)
+	}
+	a.repoName = filepath.Base(absPath)
+
+	cp, err := checkpoint.Load(a.config.Vector.Collection)
+	if err != nil {
+		return fmt.Errorf("failed to load checkpoint: %w", err)
+	}
+
+	w, err := indexer.NewWatcher(a.indexer, absPath, 250*time.Millisecond)
+	if err != nil {
+		return fmt.Errorf("failed to start filesystem watcher: %w", err)
+	}
+
+	return w.Run(ctx, func(ev indexer.Event) {
+		prev := cp.FileMap()[ev.Path]
+
+		if ev.Removed {
+			if err := a.retriever.ApplyUpdate(ctx, nil, prev.ChunkIDs); err != nil {
+				fmt.Printf("⚠️  Failed to remove %s from index: %v\n", ev.Path, err)
+				return
+			}
+			if err := cp.UpdateFile(ev.Path, 0, nil); err != nil {
+				fmt.Printf("⚠️  Failed to update checkpoint for %s: %v\n", ev.Path, err)
+			}
+			return
+		}
+
+		if err := a.retriever.ApplyUpdate(ctx, ev.Chunks, prev.ChunkIDs); err != nil {
+			fmt.Printf("⚠️  Failed to re-index %s: %v\n", ev.Path, err)
+			return
+		}
+
+		ids := make([]string, len(ev.Chunks))
+		for i, c := range ev.Chunks {
+			ids[i] = c.ID
+		}
+
+		var mtime int64
+		if info, statErr := os.Stat(filepath.Join(absPath, ev.Path)); statErr == nil {
+			mtime = info.ModTime().UnixNano()
+		}
+
+		if err := cp.UpdateFile(ev.Path, mtime, ids); err != nil {
+			fmt.Printf("⚠️  Failed to update checkpoint for %s: %v\n", ev.Path, err)
+		}
+	})
+}
+
+// rebuildGraph resolves chunks' references into a reference graph, installs
+// it on the retriever, and persists it to the graph.json sidecar so the next
+// run's NewRAGAgent picks it up without re-indexing. Failing to save is
+// logged and otherwise ignored, the same "don't fail the whole run" handling
+// Watch uses for its per-file errors, since the graph only improves ranking
+// and was never required for indexing to succeed.
+func (a *RAGAgent) rebuildGraph(chunks []*indexer.CodeChunk) {
+	graph := indexer.BuildGraph(chunks)
+	a.retriever.SetGraph(graph)
+	if err := indexer.SaveGraph(graph); err != nil {
+		fmt.Printf("⚠️  Failed to save reference graph: %v\n", err)
+	}
+}
+
+// recordFileStates persists the mtime and chunk IDs of every file in result
+// so the next IndexResume call can tell which files changed.
+func recordFileStates(cp *checkpoint.State, absPath string, result *indexer.IndexResult) error {
+	chunksByFile := make(map[string][]string)
+	for _, c := range result.Chunks {
+		chunksByFile[c.FilePath] = append(chunksByFile[c.FilePath], c.ID)
+	}
+
+	files := make([]checkpoint.FileState, 0, len(chunksByFile))
+	for relPath, ids := range chunksByFile {
+		info, err := os.Stat(filepath.Join(absPath, relPath))
+		if err != nil {
+			continue
+		}
+		files = append(files, checkpoint.FileState{
+			Path:     relPath,
+			ModTime:  info.ModTime().UnixNano(),
+			ChunkIDs: ids,
+		})
+	}
+
+	return cp.SetFiles(files)
+}
+
 // Ask asks a question about the indexed codebase
 func (a *RAGAgent) Ask(ctx context.Context, question string) (string, error) {
-	// Retrieve relevant code chunks
-	results, err := a.retriever.Search(ctx, question, 5)
+	// Retrieve relevant code chunks, expanded with their 1-hop reference
+	// graph neighbors so "how is X used?" questions pull in callers/callees
+	// alongside the direct hit.
+	results, err := a.retriever.SearchWithGraphExpand(ctx, question, 5, graphExpandBudget)
 	if err != nil {
 		return "", fmt.Errorf("retrieval failed: %w", err)
 	}
@@ -155,8 +526,9 @@ func (a *RAGAgent) Ask(ctx context.Context, question string) (string, error) {
 
 // AskStream asks a question and streams the response
 func (a *RAGAgent) AskStream(ctx context.Context, question string, handler llm.StreamHandler) error {
-	// Retrieve relevant code chunks
-	results, err := a.retriever.Search(ctx, question, 5)
+	// Retrieve relevant code chunks, expanded with their 1-hop reference
+	// graph neighbors (see Ask).
+	results, err := a.retriever.SearchWithGraphExpand(ctx, question, 5, graphExpandBudget)
 	if err != nil {
 		return fmt.Errorf("retrieval failed: %w", err)
 	}
@@ -283,14 +655,41 @@ func (a *RAGAgent) CheckHealth(ctx context.Context) error {
 
 // Close closes the agent and releases resources
 func (a *RAGAgent) Close() error {
+	if a.embedCache != nil {
+		_ = a.embedCache.Close()
+	}
 	return a.vectorStore.Close()
 }
 
+// Checkpoint durably flushes the vector store's current state without
+// closing it, so the API server can call it on session/server shutdown and
+// still leave the store usable (unlike Close).
+func (a *RAGAgent) Checkpoint() error {
+	return a.vectorStore.Checkpoint()
+}
+
+// CacheStats returns embedding cache hit/miss/byte counters, or a zero
+// value if no cache is installed (NewRAGAgent opens one for every embedding
+// provider, so that happens only when opening the cache file failed).
+func (a *RAGAgent) CacheStats() embedcache.Stats {
+	if a.embedCache == nil {
+		return embedcache.Stats{}
+	}
+	return a.embedCache.Stats()
+}
+
 // GetRetrievedChunks returns chunks for a query (for debugging/display)
 func (a *RAGAgent) GetRetrievedChunks(ctx context.Context, query string, topK int) ([]*retriever.SearchResult, error) {
 	return a.retriever.Search(ctx, query, topK)
 }
 
+// GetRetrievedChunksReranked is GetRetrievedChunks, but passed through the
+// installed Reranker (see HybridRetriever.SetReranker) for callers willing
+// to pay its extra latency for better precision at the top of the list.
+func (a *RAGAgent) GetRetrievedChunksReranked(ctx context.Context, query string, topK int) ([]*retriever.SearchResult, error) {
+	return a.retriever.SearchWithRerank(ctx, query, topK)
+}
+
 // ClearIndex clears the vector store index
 func (a *RAGAgent) ClearIndex() error {
 	return a.vectorStore.Clear()
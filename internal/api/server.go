@@ -2,31 +2,40 @@ package api
 
 import (
 	"context"
+	"crypto/sha256"
 	"fmt"
 	"net/http"
+	"os"
+	"os/signal"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/codementor/codementor/internal/agent"
 	"github.com/codementor/codementor/internal/config"
+	"github.com/codementor/codementor/internal/retriever"
 	"github.com/gin-gonic/gin"
 )
 
+// sessionJanitorInterval is how often the background janitor goroutine
+// sweeps Server.sessions for entries whose idle deadline has fired.
+const sessionJanitorInterval = 30 * time.Second
+
 // Server represents the HTTP API server
 type Server struct {
-	config   *config.Config
-	router   *gin.Engine
-	sessions map[string]*Session
-	mu       sync.RWMutex
-}
-
-// Session represents a chat session with RAG agent
-type Session struct {
-	ID        string
-	Agent     *agent.RAGAgent
-	RepoPath  string
-	CreatedAt time.Time
-	LastUsed  time.Time
+	config        *config.Config
+	router        *gin.Engine
+	sessions      map[string]*Session
+	mu            sync.RWMutex
+	httpServer    *http.Server
+	janitorCancel context.CancelFunc
+
+	// sessionDir is non-nil when config.Vector.Type == "redis": several API
+	// server replicas behind a load balancer then share the same
+	// Redis-backed index, and a session ID is just a deterministic
+	// reference to a repo prefix in it rather than data owned by one
+	// replica's in-memory sessions map (see resolveSession).
+	sessionDir *sessionDirectory
 }
 
 // NewServer creates a new API server
@@ -34,15 +43,56 @@ func NewServer(cfg *config.Config) *Server {
 	gin.SetMode(gin.ReleaseMode)
 
 	s := &Server{
-		config:   cfg,
-		router:   gin.New(),
-		sessions: make(map[string]*Session),
+		config:     cfg,
+		router:     gin.New(),
+		sessions:   make(map[string]*Session),
+		sessionDir: newSessionDirectory(cfg),
 	}
 
 	s.setupRoutes()
+
+	janitorCtx, cancel := context.WithCancel(context.Background())
+	s.janitorCancel = cancel
+	go s.runJanitor(janitorCtx)
+
 	return s
 }
 
+// runJanitor periodically evicts sessions whose idle deadline has fired,
+// closing their agent so the underlying vector store/WAL is released.
+func (s *Server) runJanitor(ctx context.Context) {
+	ticker := time.NewTicker(sessionJanitorInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.sweepExpiredSessions()
+		}
+	}
+}
+
+// sweepExpiredSessions removes and closes every session whose idle deadline
+// has already fired.
+func (s *Server) sweepExpiredSessions() {
+	s.mu.Lock()
+	var expired []*Session
+	for id, session := range s.sessions {
+		if session.Expired() {
+			expired = append(expired, session)
+			delete(s.sessions, id)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, session := range expired {
+		fmt.Printf("⏱️  Evicting idle session %s\n", session.ID)
+		session.Agent.Close()
+	}
+}
+
 // setupRoutes configures all API routes
 func (s *Server) setupRoutes() {
 	s.router.Use(gin.Recovery())
@@ -71,11 +121,106 @@ func (s *Server) setupRoutes() {
 	}
 }
 
-// Run starts the server
+// Run starts the server and blocks until it's shut down, either by Shutdown
+// being called directly or by receiving SIGINT/SIGTERM.
 func (s *Server) Run() error {
 	addr := fmt.Sprintf("%s:%d", s.config.Server.Host, s.config.Server.Port)
+	s.httpServer = &http.Server{Addr: addr, Handler: s.router}
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigCh
+		fmt.Println("🛑 Shutting down CodeMentor API server...")
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := s.Shutdown(ctx); err != nil {
+			fmt.Printf("⚠️  Error during shutdown: %v\n", err)
+		}
+	}()
+
 	fmt.Printf("🚀 Starting CodeMentor API server on %s\n", addr)
-	return s.router.Run(addr)
+	if err := s.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// Shutdown checkpoints every active session's vector store (so in-flight
+// WAL-backed writes are durable even though the sessions themselves stay
+// open) and then gracefully stops the HTTP server, waiting for in-flight
+// requests to finish or ctx to expire.
+func (s *Server) Shutdown(ctx context.Context) error {
+	if s.janitorCancel != nil {
+		s.janitorCancel()
+	}
+
+	s.mu.RLock()
+	for id, session := range s.sessions {
+		if err := session.Agent.Checkpoint(); err != nil {
+			fmt.Printf("⚠️  Failed to checkpoint session %s: %v\n", id, err)
+		}
+	}
+	s.mu.RUnlock()
+
+	if s.sessionDir != nil {
+		s.sessionDir.Close()
+	}
+
+	if s.httpServer == nil {
+		return nil
+	}
+	return s.httpServer.Shutdown(ctx)
+}
+
+// resolveSession returns the session for id, checking this replica's local
+// sessions map first. On a miss, if sessionDir is set (Vector.Type ==
+// "redis"), it looks up which repo path id was registered for and lazily
+// rehydrates a local Session pointed at the same repo prefix — so a
+// request that lands on a replica that never handled the original POST
+// /sessions call still succeeds, instead of 404ing just because this
+// process's sessions map happens to be empty for that ID. The embedded
+// chunks themselves are already in the shared RedisStore; IndexResume just
+// has to re-derive the cheap local state (BM25, chat history) on top of it.
+func (s *Server) resolveSession(ctx context.Context, sessionID string) (*Session, bool) {
+	s.mu.RLock()
+	session, exists := s.sessions[sessionID]
+	s.mu.RUnlock()
+	if exists {
+		return session, true
+	}
+
+	if s.sessionDir == nil {
+		return nil, false
+	}
+
+	repoPath, ok := s.sessionDir.Lookup(ctx, sessionID)
+	if !ok {
+		return nil, false
+	}
+
+	ragAgent := agent.NewRAGAgent(s.config)
+	if err := ragAgent.IndexResume(ctx, repoPath); err != nil {
+		ragAgent.Close()
+		return nil, false
+	}
+
+	rehydrated := newSession(sessionID, ragAgent, repoPath)
+	if ttl := s.config.Server.SessionIdleTTLSeconds; ttl > 0 {
+		rehydrated.SetIdleDeadline(time.Now().Add(time.Duration(ttl) * time.Second))
+	}
+
+	s.mu.Lock()
+	if existing, ok := s.sessions[sessionID]; ok {
+		// Lost a race with a concurrent rehydrate on this same replica.
+		s.mu.Unlock()
+		ragAgent.Close()
+		return existing, true
+	}
+	s.sessions[sessionID] = rehydrated
+	s.mu.Unlock()
+
+	return rehydrated, true
 }
 
 // corsMiddleware adds CORS headers
@@ -130,6 +275,63 @@ func (s *Server) handleCreateSession(c *gin.Context) {
 
 	ctx := c.Request.Context()
 
+	// With a shared Redis-backed index, the session ID is a deterministic
+	// reference to the repo path rather than a per-call unique ID, so two
+	// replicas (or two overlapping requests hitting the same replica) that
+	// both get a POST /sessions for the same repo converge on the same
+	// session instead of each standing up its own redundant agent.
+	if s.sessionDir != nil {
+		sessionID := fmt.Sprintf("session_%x", sha256.Sum256([]byte(req.RepoPath)))
+
+		s.mu.RLock()
+		existing, exists := s.sessions[sessionID]
+		s.mu.RUnlock()
+
+		if exists {
+			existing.touch(time.Duration(s.config.Server.SessionIdleTTLSeconds) * time.Second)
+			c.JSON(http.StatusOK, gin.H{
+				"session_id":  sessionID,
+				"repo_path":   existing.RepoPath,
+				"chunk_count": existing.Agent.GetChunkCount(),
+				"created_at":  existing.CreatedAt,
+			})
+			return
+		}
+
+		ragAgent := agent.NewRAGAgent(s.config)
+		if err := ragAgent.CheckHealth(ctx); err != nil {
+			ragAgent.Close()
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Ollama not available"})
+			return
+		}
+		if err := ragAgent.IndexResume(ctx, req.RepoPath); err != nil {
+			ragAgent.Close()
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Failed to index: %v", err)})
+			return
+		}
+
+		session := newSession(sessionID, ragAgent, req.RepoPath)
+		if ttl := s.config.Server.SessionIdleTTLSeconds; ttl > 0 {
+			session.SetIdleDeadline(time.Now().Add(time.Duration(ttl) * time.Second))
+		}
+
+		s.mu.Lock()
+		s.sessions[sessionID] = session
+		s.mu.Unlock()
+
+		if err := s.sessionDir.Register(ctx, sessionID, req.RepoPath); err != nil {
+			fmt.Printf("⚠️  Failed to register session %s in directory: %v\n", sessionID, err)
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"session_id":  sessionID,
+			"repo_path":   req.RepoPath,
+			"chunk_count": ragAgent.GetChunkCount(),
+			"created_at":  session.CreatedAt,
+		})
+		return
+	}
+
 	// Create new agent
 	ragAgent := agent.NewRAGAgent(s.config)
 
@@ -140,8 +342,11 @@ func (s *Server) handleCreateSession(c *gin.Context) {
 		return
 	}
 
-	// Index repository
-	err := ragAgent.IndexRepository(ctx, req.RepoPath, nil)
+	// Index repository. IndexResume (not IndexRepository) so overlapping
+	// POST /sessions calls against the same repo compare-and-swap each
+	// chunk instead of racing to rebuild the whole index (see
+	// VectorStore.CompareAndSwap).
+	err := ragAgent.IndexResume(ctx, req.RepoPath)
 	if err != nil {
 		ragAgent.Close()
 		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Failed to index: %v", err)})
@@ -150,12 +355,9 @@ func (s *Server) handleCreateSession(c *gin.Context) {
 
 	// Create session
 	sessionID := fmt.Sprintf("session_%d", time.Now().UnixNano())
-	session := &Session{
-		ID:        sessionID,
-		Agent:     ragAgent,
-		RepoPath:  req.RepoPath,
-		CreatedAt: time.Now(),
-		LastUsed:  time.Now(),
+	session := newSession(sessionID, ragAgent, req.RepoPath)
+	if ttl := s.config.Server.SessionIdleTTLSeconds; ttl > 0 {
+		session.SetIdleDeadline(time.Now().Add(time.Duration(ttl) * time.Second))
 	}
 
 	s.mu.Lock()
@@ -163,10 +365,10 @@ func (s *Server) handleCreateSession(c *gin.Context) {
 	s.mu.Unlock()
 
 	c.JSON(http.StatusOK, gin.H{
-		"session_id":   sessionID,
-		"repo_path":    req.RepoPath,
-		"chunk_count":  ragAgent.GetChunkCount(),
-		"created_at":   session.CreatedAt,
+		"session_id":  sessionID,
+		"repo_path":   req.RepoPath,
+		"chunk_count": ragAgent.GetChunkCount(),
+		"created_at":  session.CreatedAt,
 	})
 }
 
@@ -177,6 +379,9 @@ func (s *Server) handleDeleteSession(c *gin.Context) {
 	s.mu.Lock()
 	session, exists := s.sessions[sessionID]
 	if exists {
+		if err := session.Agent.Checkpoint(); err != nil {
+			fmt.Printf("⚠️  Failed to checkpoint session %s before delete: %v\n", sessionID, err)
+		}
 		session.Agent.Close()
 		delete(s.sessions, sessionID)
 	}
@@ -187,6 +392,12 @@ func (s *Server) handleDeleteSession(c *gin.Context) {
 		return
 	}
 
+	if s.sessionDir != nil {
+		if err := s.sessionDir.Forget(c.Request.Context(), sessionID); err != nil {
+			fmt.Printf("⚠️  Failed to remove session %s from directory: %v\n", sessionID, err)
+		}
+	}
+
 	c.JSON(http.StatusOK, gin.H{"message": "Session deleted"})
 }
 
@@ -194,10 +405,7 @@ func (s *Server) handleDeleteSession(c *gin.Context) {
 func (s *Server) handleGetSession(c *gin.Context) {
 	sessionID := c.Param("id")
 
-	s.mu.RLock()
-	session, exists := s.sessions[sessionID]
-	s.mu.RUnlock()
-
+	session, exists := s.resolveSession(c.Request.Context(), sessionID)
 	if !exists {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
 		return
@@ -226,16 +434,14 @@ func (s *Server) handleChat(c *gin.Context) {
 		return
 	}
 
-	s.mu.RLock()
-	session, exists := s.sessions[req.SessionID]
-	s.mu.RUnlock()
-
+	session, exists := s.resolveSession(c.Request.Context(), req.SessionID)
 	if !exists {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
 		return
 	}
 
-	ctx := c.Request.Context()
+	ctx, cancel := session.SetOperationDeadline(c.Request.Context())
+	defer cancel()
 
 	response, err := session.Agent.Ask(ctx, req.Message)
 	if err != nil {
@@ -243,10 +449,7 @@ func (s *Server) handleChat(c *gin.Context) {
 		return
 	}
 
-	// Update last used
-	s.mu.Lock()
-	session.LastUsed = time.Now()
-	s.mu.Unlock()
+	session.touch(time.Duration(s.config.Server.SessionIdleTTLSeconds) * time.Second)
 
 	c.JSON(http.StatusOK, gin.H{
 		"response": response,
@@ -263,10 +466,7 @@ func (s *Server) handleChatStream(c *gin.Context) {
 		return
 	}
 
-	s.mu.RLock()
-	session, exists := s.sessions[sessionID]
-	s.mu.RUnlock()
-
+	session, exists := s.resolveSession(c.Request.Context(), sessionID)
 	if !exists {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
 		return
@@ -277,10 +477,19 @@ func (s *Server) handleChatStream(c *gin.Context) {
 	c.Header("Cache-Control", "no-cache")
 	c.Header("Connection", "keep-alive")
 
-	ctx := c.Request.Context()
+	ctx, cancel := session.SetOperationDeadline(c.Request.Context())
+	defer cancel()
 
-	// Stream response
+	// Stream response, checking ctx before each frame so a disconnected
+	// client or an idle-expired session stops pushing frames instead of
+	// blocking on c.Writer.Flush() forever.
 	err := session.Agent.AskStream(ctx, message, func(content string, done bool) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
 		if done {
 			c.SSEvent("done", "")
 		} else {
@@ -290,15 +499,12 @@ func (s *Server) handleChatStream(c *gin.Context) {
 		return nil
 	})
 
-	if err != nil {
+	if err != nil && ctx.Err() == nil {
 		c.SSEvent("error", err.Error())
 		c.Writer.Flush()
 	}
 
-	// Update last used
-	s.mu.Lock()
-	session.LastUsed = time.Now()
-	s.mu.Unlock()
+	session.touch(time.Duration(s.config.Server.SessionIdleTTLSeconds) * time.Second)
 }
 
 // IndexRequest represents an index request
@@ -324,8 +530,13 @@ func (s *Server) handleIndex(c *gin.Context) {
 		return
 	}
 
+	// IndexResume (not IndexRepository): a repeated POST /index against a
+	// repo that's already indexed compare-and-swaps only the chunks whose
+	// content actually changed instead of skipping (or re-embedding)
+	// wholesale, and several concurrent calls against the same repo can't
+	// corrupt the store (see VectorStore.CompareAndSwap).
 	startTime := time.Now()
-	err := ragAgent.IndexRepository(ctx, req.RepoPath, nil)
+	err := ragAgent.IndexResume(ctx, req.RepoPath)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -343,6 +554,7 @@ type SearchRequest struct {
 	SessionID string `json:"session_id" binding:"required"`
 	Query     string `json:"query" binding:"required"`
 	TopK      int    `json:"top_k"`
+	Rerank    bool   `json:"rerank"` // Pass results through the installed Reranker; costs an extra LLM call per candidate
 }
 
 // handleSearch handles code search requests
@@ -357,23 +569,29 @@ func (s *Server) handleSearch(c *gin.Context) {
 		req.TopK = 5
 	}
 
-	s.mu.RLock()
-	session, exists := s.sessions[req.SessionID]
-	s.mu.RUnlock()
-
+	session, exists := s.resolveSession(c.Request.Context(), req.SessionID)
 	if !exists {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
 		return
 	}
 
-	ctx := c.Request.Context()
+	ctx, cancel := session.SetOperationDeadline(c.Request.Context())
+	defer cancel()
 
-	results, err := session.Agent.GetRetrievedChunks(ctx, req.Query, req.TopK)
+	var results []*retriever.SearchResult
+	var err error
+	if req.Rerank {
+		results, err = session.Agent.GetRetrievedChunksReranked(ctx, req.Query, req.TopK)
+	} else {
+		results, err = session.Agent.GetRetrievedChunks(ctx, req.Query, req.TopK)
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	session.touch(time.Duration(s.config.Server.SessionIdleTTLSeconds) * time.Second)
+
 	// Format results
 	var formattedResults []gin.H
 	for _, r := range results {
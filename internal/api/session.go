@@ -0,0 +1,132 @@
+package api
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/codementor/codementor/internal/agent"
+)
+
+// Session represents a chat session with RAG agent. Its idle-deadline
+// machinery is modeled on the deadlineTimer pattern net.Conn implementations
+// use (gonet's internal/poll/fd_poll_runtime.go): a timer that, when it
+// fires, closes a cancellation channel so anything selecting on it unblocks
+// immediately instead of waiting for the operation to end on its own.
+type Session struct {
+	ID        string
+	Agent     *agent.RAGAgent
+	RepoPath  string
+	CreatedAt time.Time
+	LastUsed  time.Time
+
+	mu            sync.Mutex
+	idleTimer     *time.Timer
+	readCancelCh  chan struct{}
+	writeCancelCh chan struct{}
+	expired       bool
+}
+
+// newSession creates a Session with its cancellation channels armed but no
+// idle deadline set yet; the caller sets one via SetIdleDeadline.
+func newSession(id string, ragAgent *agent.RAGAgent, repoPath string) *Session {
+	now := time.Now()
+	return &Session{
+		ID:            id,
+		Agent:         ragAgent,
+		RepoPath:      repoPath,
+		CreatedAt:     now,
+		LastUsed:      now,
+		readCancelCh:  make(chan struct{}),
+		writeCancelCh: make(chan struct{}),
+	}
+}
+
+// SetIdleDeadline arms (or re-arms, pushing it forward) the timer that closes
+// this session's cancellation channels if it fires with no activity before
+// then. touch (called from handleChat/handleChatStream/handleSearch on every
+// request) re-arms it to extend the session's life; the server's janitor
+// goroutine watches Expired() and evicts sessions once it's fired. A t in
+// the past (or a zero value) expires the session immediately.
+func (s *Session) SetIdleDeadline(t time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.expired {
+		return
+	}
+
+	if s.idleTimer != nil {
+		s.idleTimer.Stop()
+	}
+
+	d := time.Until(t)
+	if d <= 0 {
+		s.expireLocked()
+		return
+	}
+
+	s.idleTimer = time.AfterFunc(d, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.expireLocked()
+	})
+}
+
+// expireLocked closes the session's cancellation channels exactly once. It
+// must be called with s.mu held.
+func (s *Session) expireLocked() {
+	if s.expired {
+		return
+	}
+	s.expired = true
+	close(s.readCancelCh)
+	close(s.writeCancelCh)
+}
+
+// Expired reports whether this session's idle deadline has already fired.
+func (s *Session) Expired() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.expired
+}
+
+// SetOperationDeadline derives a context from ctx that's additionally
+// cancelled the instant this session's idle deadline fires, so an in-flight
+// Ask/AskStream/GetRetrievedChunks call is cancelled cleanly as soon as
+// either the HTTP client disconnects (ctx.Done) or the session goes
+// idle-expired out from under it, instead of running to completion against
+// a session the janitor is about to tear down. The caller must call the
+// returned cancel func once the operation finishes.
+func (s *Session) SetOperationDeadline(ctx context.Context) (context.Context, context.CancelFunc) {
+	derived, cancel := context.WithCancel(ctx)
+
+	s.mu.Lock()
+	readCh := s.readCancelCh
+	writeCh := s.writeCancelCh
+	s.mu.Unlock()
+
+	go func() {
+		select {
+		case <-derived.Done():
+		case <-readCh:
+			cancel()
+		case <-writeCh:
+			cancel()
+		}
+	}()
+
+	return derived, cancel
+}
+
+// touch refreshes LastUsed and pushes the idle deadline forward by ttl
+// (a zero or negative ttl disables the deadline entirely).
+func (s *Session) touch(ttl time.Duration) {
+	s.mu.Lock()
+	s.LastUsed = time.Now()
+	s.mu.Unlock()
+
+	if ttl > 0 {
+		s.SetIdleDeadline(time.Now().Add(ttl))
+	}
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/codementor/codementor/internal/config"
+)
+
+// sessionDirectory lets every API server replica look up which repo path a
+// session ID maps to, so a /chat or /search request that lands on a
+// replica that never saw the original POST /sessions can lazily rehydrate
+// a local Session (see Server.resolveSession) instead of 404ing. The chat
+// history/BM25/graph state that rehydration rebuilds is cheap to recompute;
+// the expensive part — the embedded chunks themselves — already lives in
+// the shared retriever.RedisStore, so this directory only needs to persist
+// the one thing a fresh replica can't otherwise recover: which repo the
+// session ID was for.
+type sessionDirectory struct {
+	rdb *redis.Client
+}
+
+// newSessionDirectory returns nil when cfg isn't configured for a
+// Redis-backed vector store — every other VectorStore keeps its data local
+// to whichever process built it, so there's no shared place to register
+// sessions and resolveSession falls back to its old in-process-only lookup.
+func newSessionDirectory(cfg *config.Config) *sessionDirectory {
+	if cfg.Vector.Type != "redis" {
+		return nil
+	}
+
+	return &sessionDirectory{
+		rdb: redis.NewClient(&redis.Options{
+			Addr:     fmt.Sprintf("%s:%d", cfg.Vector.Host, cfg.Vector.Port),
+			Password: cfg.Vector.Password,
+			DB:       cfg.Vector.DB,
+		}),
+	}
+}
+
+func (d *sessionDirectory) key(sessionID string) string {
+	return fmt.Sprintf("codementor:sessions:%s", sessionID)
+}
+
+// Register records that sessionID maps to repoPath.
+func (d *sessionDirectory) Register(ctx context.Context, sessionID, repoPath string) error {
+	return d.rdb.Set(ctx, d.key(sessionID), repoPath, 0).Err()
+}
+
+// Lookup returns the repo path registered for sessionID, if any.
+func (d *sessionDirectory) Lookup(ctx context.Context, sessionID string) (string, bool) {
+	repoPath, err := d.rdb.Get(ctx, d.key(sessionID)).Result()
+	if err != nil {
+		return "", false
+	}
+	return repoPath, true
+}
+
+// Forget removes sessionID's directory entry.
+func (d *sessionDirectory) Forget(ctx context.Context, sessionID string) error {
+	return d.rdb.Del(ctx, d.key(sessionID)).Err()
+}
+
+func (d *sessionDirectory) Close() error {
+	if d == nil {
+		return nil
+	}
+	return d.rdb.Close()
+}
@@ -0,0 +1,190 @@
+// Package checkpoint implements resumable-indexing state, modeled on the
+// chunked upload checkpointing used by Docker Registry's blob push protocol:
+// progress is committed to disk after every batch so an interrupted index
+// can resume from the last offset instead of starting over.
+package checkpoint
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// FileState records the mtime and chunk IDs produced for a source file the
+// last time it was committed, so a resumed index can tell which files
+// changed and which chunks to invalidate.
+type FileState struct {
+	Path     string   `json:"path"`
+	ModTime  int64    `json:"mod_time"`
+	ChunkIDs []string `json:"chunk_ids"`
+}
+
+// State is the on-disk checkpoint for a single collection's indexing run.
+type State struct {
+	Collection  string            `json:"collection"`
+	LastOffset  int               `json:"last_offset"`
+	ChunkID     string            `json:"chunk_id"`
+	ContentHash string            `json:"content_hash"`
+	Committed   map[string]string `json:"committed"` // chunk ID -> content hash
+	Files       []FileState       `json:"files"`
+
+	mu   sync.Mutex
+	path string
+}
+
+// PathFor returns the checkpoint file path for a collection.
+func PathFor(collection string) string {
+	return filepath.Join(".codementor", fmt.Sprintf("index_state_%s.json", collection))
+}
+
+// Load reads the checkpoint for a collection, returning a fresh empty State
+// if one doesn't exist yet.
+func Load(collection string) (*State, error) {
+	path := PathFor(collection)
+	s := &State{
+		Collection: collection,
+		Committed:  make(map[string]string),
+		path:       path,
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint: %w", err)
+	}
+	if s.Committed == nil {
+		s.Committed = make(map[string]string)
+	}
+	s.path = path
+
+	return s, nil
+}
+
+// Commit records a successfully embedded chunk's offset and persists the
+// checkpoint to disk immediately. It is safe to call concurrently.
+func (s *State) Commit(offset int, chunkID, contentHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.LastOffset = offset
+	s.ChunkID = chunkID
+	s.ContentHash = contentHash
+	s.Committed[chunkID] = contentHash
+
+	return s.save()
+}
+
+// Skip reports whether a chunk with the given ID and content hash was
+// already committed in a previous run, so callers can avoid re-embedding it.
+func (s *State) Skip(chunkID, contentHash string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.Committed[chunkID] == contentHash
+}
+
+// SetFiles replaces the recorded file/mtime/chunk-IDs list and persists it.
+// IndexResume uses this to know which files it needs to re-parse next time.
+func (s *State) SetFiles(files []FileState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Files = files
+	return s.save()
+}
+
+// UpdateFile records (or removes, if chunkIDs is empty) the state of a
+// single file and persists it, without rewriting every other file's entry.
+// The filesystem watcher uses this to checkpoint one changed file at a time
+// instead of re-scanning the whole tree like SetFiles does.
+func (s *State) UpdateFile(path string, modTime int64, chunkIDs []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	idx := -1
+	for i, f := range s.Files {
+		if f.Path == path {
+			idx = i
+			break
+		}
+	}
+
+	if len(chunkIDs) == 0 {
+		if idx >= 0 {
+			s.Files = append(s.Files[:idx], s.Files[idx+1:]...)
+		}
+		return s.save()
+	}
+
+	entry := FileState{Path: path, ModTime: modTime, ChunkIDs: chunkIDs}
+	if idx >= 0 {
+		s.Files[idx] = entry
+	} else {
+		s.Files = append(s.Files, entry)
+	}
+
+	return s.save()
+}
+
+// FileMap returns the recorded files keyed by path for quick lookup.
+func (s *State) FileMap() map[string]FileState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m := make(map[string]FileState, len(s.Files))
+	for _, f := range s.Files {
+		m[f.Path] = f
+	}
+	return m
+}
+
+// Clear resets the committed chunks, file list and offset, and removes the
+// checkpoint file.
+func (s *State) Clear() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Committed = make(map[string]string)
+	s.Files = nil
+	s.LastOffset = 0
+
+	if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// save writes the checkpoint to disk via a rename so a crash mid-write never
+// leaves a corrupt checkpoint behind. Callers must hold s.mu.
+func (s *State) save() error {
+	dir := filepath.Dir(s.path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create checkpoint directory: %w", err)
+	}
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("failed to marshal checkpoint: %w", err)
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+
+	return os.Rename(tmp, s.path)
+}
+
+// HashContent returns the content hash used to key checkpoint entries.
+func HashContent(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
@@ -22,21 +22,47 @@ type EmbeddingConfig struct {
 	Host     string `mapstructure:"host"`     // For codebert service
 }
 
-// OllamaConfig holds Ollama-related configuration
+// OllamaConfig holds the chat/embedding backend configuration. Despite the
+// name (kept for config-file compatibility), Provider selects which
+// llm.Provider implementation Host/ChatModel/EmbeddingModel are interpreted
+// by: "ollama" (default) talks to Ollama's native API, "openai" talks to any
+// OpenAI-compatible endpoint (vLLM, LM Studio, llama.cpp server, hosted
+// OpenAI) at Host, authenticating with APIKey as a bearer token.
 type OllamaConfig struct {
 	Host           string `mapstructure:"host"`
 	ChatModel      string `mapstructure:"chat_model"`
 	EmbeddingModel string `mapstructure:"embedding_model"`
-	Timeout        int    `mapstructure:"timeout"` // seconds
+	Timeout        int    `mapstructure:"timeout"`  // seconds
+	Provider       string `mapstructure:"provider"` // ollama, openai
+	APIKey         string `mapstructure:"api_key"`   // bearer token, openai provider only
 }
 
 // VectorConfig holds vector database configuration
 type VectorConfig struct {
-	Type       string `mapstructure:"type"` // milvus, qdrant, memory
+	Type       string `mapstructure:"type"` // milvus, qdrant, memory, bolt, objstore, redis
 	Host       string `mapstructure:"host"`
 	Port       int    `mapstructure:"port"`
 	Collection string `mapstructure:"collection"`
 	Dimension  int    `mapstructure:"dimension"`
+
+	// Object-storage fields, used when Type == "objstore".
+	ObjectProvider string `mapstructure:"object_provider"` // s3, gcs, swift
+	Endpoint       string `mapstructure:"endpoint"`
+	Bucket         string `mapstructure:"bucket"`
+	Region         string `mapstructure:"region"`
+	AccessKey      string `mapstructure:"access_key"`
+	SecretKey      string `mapstructure:"secret_key"`
+	Prefix         string `mapstructure:"prefix"` // namespaces repos sharing one bucket
+
+	// WALSegmentBytes is the write-ahead log segment size MemoryStore
+	// compacts into a checkpoint at. Only used by the memory store: Type ==
+	// "memory" (or any type NewRAGAgent doesn't recognize), or the fallback
+	// when a bolt, qdrant or redis store fails to open.
+	WALSegmentBytes int64 `mapstructure:"wal_segment_bytes"`
+
+	// Redis fields, used when Type == "redis".
+	Password string `mapstructure:"password"`
+	DB       int    `mapstructure:"db"`
 }
 
 // IndexerConfig holds code indexing configuration
@@ -51,6 +77,10 @@ type IndexerConfig struct {
 type ServerConfig struct {
 	Host string `mapstructure:"host"`
 	Port int    `mapstructure:"port"`
+
+	// SessionIdleTTLSeconds is how long a session can sit with no chat/search
+	// activity before the janitor goroutine evicts it. 0 disables eviction.
+	SessionIdleTTLSeconds int `mapstructure:"session_idle_ttl_seconds"`
 }
 
 // DefaultConfig returns the default configuration
@@ -61,6 +91,7 @@ func DefaultConfig() *Config {
 			ChatModel:      "qwen2.5:7b",
 			EmbeddingModel: "nomic-embed-text",
 			Timeout:        120,
+			Provider:       "ollama",
 		},
 		Embedding: EmbeddingConfig{
 			Provider: "codebert",                 // codebert or ollama
@@ -72,6 +103,8 @@ func DefaultConfig() *Config {
 			Port:       19530,
 			Collection: "codementor",
 			Dimension:  768,
+
+			WALSegmentBytes: 8 * 1024 * 1024,
 		},
 		Indexer: IndexerConfig{
 			ChunkSize:    1000,
@@ -80,8 +113,9 @@ func DefaultConfig() *Config {
 			IgnoreDirs:   []string{".git", "node_modules", "vendor", "__pycache__", ".idea", ".vscode"},
 		},
 		Server: ServerConfig{
-			Host: "0.0.0.0",
-			Port: 8080,
+			Host:                  "0.0.0.0",
+			Port:                  8080,
+			SessionIdleTTLSeconds: 1800,
 		},
 	}
 }
@@ -0,0 +1,165 @@
+// Package embedcache provides a content-addressed cache in front of an
+// embedding client: sha256(contentHash+model) maps to the embedding vector,
+// so re-indexing a repo where only a few files changed skips re-embedding
+// everything else. It's backed by an append-only JSONL file with an
+// in-memory index, the same persistence style MemoryStore already uses.
+package embedcache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+// record is one entry as written to the append-only cache file.
+type record struct {
+	Key       string    `json:"key"`
+	Embedding []float32 `json:"embedding"`
+}
+
+// Cache maps a content-addressed key to its embedding vector.
+type Cache struct {
+	mu      sync.RWMutex
+	path    string
+	entries map[string][]float32
+	file    *os.File
+
+	hits   int64
+	misses int64
+}
+
+// New opens (creating if necessary) a cache backed by the file at path. An
+// empty path gives an in-memory-only cache, useful for tests and library
+// callers that don't want a file on disk.
+func New(path string) (*Cache, error) {
+	c := &Cache{
+		path:    path,
+		entries: make(map[string][]float32),
+	}
+
+	if path == "" {
+		return c, nil
+	}
+
+	if err := c.load(); err != nil {
+		return nil, fmt.Errorf("failed to load embed cache: %w", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open embed cache: %w", err)
+	}
+	c.file = f
+
+	return c, nil
+}
+
+// load replays every record from the append-only file into memory.
+func (c *Cache) load() error {
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for dec.More() {
+		var r record
+		if err := dec.Decode(&r); err != nil {
+			// A crash mid-append can leave a truncated trailing record;
+			// tolerate it and keep everything decoded so far.
+			break
+		}
+		c.entries[r.Key] = r.Embedding
+	}
+
+	return nil
+}
+
+// Key builds a cache key from a chunk's content hash and the embedding
+// model name, so upgrading the model automatically invalidates cached
+// vectors instead of silently mixing embeddings from two models.
+func Key(contentHash, model string) string {
+	sum := sha256.Sum256([]byte(contentHash + ":" + model))
+	return hex.EncodeToString(sum[:])
+}
+
+// Get returns the cached embedding for key, if present.
+func (c *Cache) Get(key string) ([]float32, bool) {
+	c.mu.RLock()
+	v, ok := c.entries[key]
+	c.mu.RUnlock()
+
+	if ok {
+		atomic.AddInt64(&c.hits, 1)
+	} else {
+		atomic.AddInt64(&c.misses, 1)
+	}
+	return v, ok
+}
+
+// Put stores an embedding under key and appends it to the on-disk log.
+func (c *Cache) Put(key string, embedding []float32) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, exists := c.entries[key]; exists {
+		return nil
+	}
+	c.entries[key] = embedding
+
+	if c.file == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(record{Key: key, Embedding: embedding})
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	_, err = c.file.Write(data)
+	return err
+}
+
+// Stats reports cache hits/misses and the approximate size of cached
+// vectors in memory.
+type Stats struct {
+	Hits   int64
+	Misses int64
+	Bytes  int64
+}
+
+// Stats returns a snapshot of cache hit/miss counters and memory usage.
+func (c *Cache) Stats() Stats {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var bytes int64
+	for _, v := range c.entries {
+		bytes += int64(len(v)) * 4 // float32
+	}
+
+	return Stats{
+		Hits:   atomic.LoadInt64(&c.hits),
+		Misses: atomic.LoadInt64(&c.misses),
+		Bytes:  bytes,
+	}
+}
+
+// Close closes the underlying cache file, if any.
+func (c *Cache) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.file != nil {
+		return c.file.Close()
+	}
+	return nil
+}
@@ -12,14 +12,14 @@ import (
 
 // Embedder generates embeddings for code chunks
 type Embedder struct {
-	client      *llm.Client
+	client      llm.Provider
 	batchSize   int
 	concurrency int
 	maxRetries  int
 }
 
 // NewEmbedder creates a new embedder
-func NewEmbedder(client *llm.Client) *Embedder {
+func NewEmbedder(client llm.Provider) *Embedder {
 	return &Embedder{
 		client:      client,
 		batchSize:   10,
@@ -32,10 +32,38 @@ func NewEmbedder(client *llm.Client) *Embedder {
 type EmbeddedChunk struct {
 	Chunk     *indexer.CodeChunk `json:"chunk"`
 	Embedding []float32          `json:"embedding"`
+
+	// Version is a monotonically incrementing counter a VectorStore assigns
+	// on each successful write, so VectorStore.CompareAndSwap can detect a
+	// concurrent writer racing to update the same chunk ID (see
+	// retriever.ErrVersionConflict).
+	Version uint64 `json:"version"`
+}
+
+// embedBatch groups a contiguous run of chunks embedded together in one
+// Provider.EmbedBatch call.
+type embedBatch struct {
+	chunks []*indexer.CodeChunk
 }
 
-// EmbedChunks generates embeddings for multiple chunks
+// EmbedChunks generates embeddings for multiple chunks. Chunks are grouped
+// into e.batchSize-sized batches and each batch is sent to the provider as a
+// single EmbedBatch call, with up to e.concurrency batches in flight at
+// once — true batching instead of firing one request per chunk, so a
+// batch-capable backend (vLLM, LM Studio, hosted OpenAI) only sees
+// len(chunks)/batchSize requests rather than len(chunks) of them.
 func (e *Embedder) EmbedChunks(ctx context.Context, chunks []*indexer.CodeChunk, progressFn func(current, total int)) ([]*EmbeddedChunk, error) {
+	total := len(chunks)
+
+	var batches []embedBatch
+	for i := 0; i < total; i += e.batchSize {
+		end := i + e.batchSize
+		if end > total {
+			end = total
+		}
+		batches = append(batches, embedBatch{chunks: chunks[i:end]})
+	}
+
 	var results []*EmbeddedChunk
 	var mu sync.Mutex
 	var wg sync.WaitGroup
@@ -47,52 +75,34 @@ func (e *Embedder) EmbedChunks(ctx context.Context, chunks []*indexer.CodeChunk,
 	var failedCount int
 	var errMu sync.Mutex
 
-	total := len(chunks)
-
-	for i, chunk := range chunks {
+	for _, b := range batches {
 		wg.Add(1)
-		go func(idx int, c *indexer.CodeChunk) {
+		go func(b embedBatch) {
 			defer wg.Done()
 			sem <- struct{}{}
 			defer func() { <-sem }()
 
-			// Create embedding text from chunk
-			text := e.createEmbeddingText(c)
-
-			// Generate embedding with retry
-			var embedding []float32
-			var err error
-
-			for retry := 0; retry < e.maxRetries; retry++ {
-				embedding, err = e.client.Embed(ctx, text)
-				if err == nil {
-					break
-				}
-				// Wait before retry
-				if retry < e.maxRetries-1 {
-					time.Sleep(time.Duration(retry+1) * 500 * time.Millisecond)
-				}
-			}
+			embedded, failed := e.embedWithBisect(ctx, b.chunks)
 
-			if err != nil {
+			if failed > 0 {
 				errMu.Lock()
-				failedCount++
+				failedCount += failed
 				errMu.Unlock()
+			}
+
+			if len(embedded) == 0 {
 				return
 			}
 
 			mu.Lock()
-			results = append(results, &EmbeddedChunk{
-				Chunk:     c,
-				Embedding: embedding,
-			})
+			results = append(results, embedded...)
 
 			// Report progress
 			if progressFn != nil {
 				progressFn(len(results), total)
 			}
 			mu.Unlock()
-		}(i, chunk)
+		}(b)
 	}
 
 	wg.Wait()
@@ -110,6 +120,49 @@ func (e *Embedder) EmbedChunks(ctx context.Context, chunks []*indexer.CodeChunk,
 	return results, nil
 }
 
+// embedWithBisect embeds chunks in a single EmbedBatch call, retrying up to
+// e.maxRetries times. If the whole batch still fails, it bisects into two
+// halves and recurses — the same fallback llm.CodeBERTClient.EmbedBatch uses
+// — so one chunk the provider rejects (e.g. for exceeding its context limit)
+// doesn't sink every other chunk sharing its batch.
+func (e *Embedder) embedWithBisect(ctx context.Context, chunks []*indexer.CodeChunk) ([]*EmbeddedChunk, int) {
+	texts := make([]string, len(chunks))
+	for i, c := range chunks {
+		texts[i] = e.createEmbeddingText(c)
+	}
+
+	var embeddings [][]float32
+	var err error
+
+	for retry := 0; retry < e.maxRetries; retry++ {
+		embeddings, err = e.client.EmbedBatch(ctx, texts)
+		if err == nil {
+			break
+		}
+		// Wait before retry
+		if retry < e.maxRetries-1 {
+			time.Sleep(time.Duration(retry+1) * 500 * time.Millisecond)
+		}
+	}
+
+	if err == nil {
+		results := make([]*EmbeddedChunk, len(chunks))
+		for i, c := range chunks {
+			results[i] = &EmbeddedChunk{Chunk: c, Embedding: embeddings[i]}
+		}
+		return results, 0
+	}
+
+	if len(chunks) == 1 {
+		return nil, 1
+	}
+
+	mid := len(chunks) / 2
+	left, leftFailed := e.embedWithBisect(ctx, chunks[:mid])
+	right, rightFailed := e.embedWithBisect(ctx, chunks[mid:])
+	return append(left, right...), leftFailed + rightFailed
+}
+
 // createEmbeddingText creates the text to embed for a chunk
 // This includes relevant metadata to improve retrieval quality
 func (e *Embedder) createEmbeddingText(chunk *indexer.CodeChunk) string {
@@ -130,7 +183,7 @@ func (e *Embedder) createEmbeddingText(chunk *indexer.CodeChunk) string {
 		}
 		text += fmt.Sprintf("Code:\n%s", chunk.Content)
 
-	case indexer.ChunkTypeStruct, indexer.ChunkTypeInterface:
+	case indexer.ChunkTypeStruct, indexer.ChunkTypeClass, indexer.ChunkTypeInterface:
 		text = fmt.Sprintf("File: %s\nType: %s\nName: %s\n", chunk.FilePath, chunk.ChunkType, chunk.Name)
 		if chunk.DocComment != "" {
 			text += fmt.Sprintf("Documentation: %s\n", chunk.DocComment)
@@ -158,3 +211,94 @@ func (e *Embedder) GetEmbeddingDimension(ctx context.Context) (int, error) {
 	}
 	return len(testEmbed), nil
 }
+
+// VectorStore is the subset of retriever.VectorStore that MigrateStore
+// needs. It's declared locally instead of importing internal/retriever,
+// since that package already imports this one for EmbeddedChunk — any
+// retriever.VectorStore implementation satisfies this interface too.
+type VectorStore interface {
+	All() ([]*EmbeddedChunk, error)
+	Get(id string) (*EmbeddedChunk, bool)
+	CompareAndSwap(id string, expectedVersion uint64, new *EmbeddedChunk) error
+}
+
+// MigrateStore hot-swaps the embedder's model to newModel and, if that
+// changes the embedding dimension, re-embeds every chunk already in store
+// and swaps each one in with CompareAndSwap. Dimension is the cheapest
+// reliable signal that vectors need migrating at all: switching to a model
+// with the same output size still produces vectors that aren't comparable
+// to the old ones, but re-embedding the whole store on every
+// SetEmbeddingModel call (even a same-dimension one a caller only meant to
+// try out) would defeat the point of a fast hot-swap, so MigrateStore only
+// pays that cost when the store would otherwise end up with
+// mismatched-dimension vectors it can't even search against.
+func (e *Embedder) MigrateStore(ctx context.Context, newModel string, store VectorStore) error {
+	chunks, err := store.All()
+	if err != nil {
+		return fmt.Errorf("failed to list existing chunks: %w", err)
+	}
+
+	if err := e.client.SetEmbeddingModel(ctx, newModel); err != nil {
+		return fmt.Errorf("failed to switch embedding model to %s: %w", newModel, err)
+	}
+
+	if len(chunks) == 0 {
+		return nil
+	}
+
+	oldDimension := len(chunks[0].Embedding)
+	newDimension, err := e.GetEmbeddingDimension(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to probe %s dimension: %w", newModel, err)
+	}
+
+	if newDimension == oldDimension {
+		return nil
+	}
+
+	codeChunks := make([]*indexer.CodeChunk, len(chunks))
+	for i, c := range chunks {
+		codeChunks[i] = c.Chunk
+	}
+
+	reEmbedded, err := e.EmbedChunks(ctx, codeChunks, nil)
+	if err != nil {
+		return fmt.Errorf("failed to re-embed chunks with %s: %w", newModel, err)
+	}
+
+	// Unlike hybrid.go's tryUpdateChunk, this doesn't retry on
+	// ErrVersionConflict: MigrateStore is a one-shot administrative
+	// operation, not something expected to race against a concurrent
+	// indexing run, so a conflict here means something else mutated the
+	// store mid-migration and is surfaced as an error instead of silently
+	// retried.
+	migrated := make(map[string]bool, len(reEmbedded))
+	for _, chunk := range reEmbedded {
+		existing, ok := store.Get(chunk.Chunk.ID)
+		var expectedVersion uint64
+		if ok {
+			expectedVersion = existing.Version
+		}
+		if err := store.CompareAndSwap(chunk.Chunk.ID, expectedVersion, chunk); err != nil {
+			return fmt.Errorf("failed to swap migrated chunk %s: %w", chunk.Chunk.ID, err)
+		}
+		migrated[chunk.Chunk.ID] = true
+	}
+
+	// EmbedChunks tolerates up to ~50% embedding failures without returning
+	// an error, so reEmbedded can be shorter than codeChunks even on a "nil
+	// error" return. Left unchecked, those chunks would silently keep their
+	// oldDimension vectors, now mismatched, forever while MigrateStore
+	// reports success — so any gap is surfaced here instead.
+	if len(reEmbedded) < len(codeChunks) {
+		var missing []string
+		for _, c := range codeChunks {
+			if !migrated[c.ID] {
+				missing = append(missing, c.ID)
+			}
+		}
+		return fmt.Errorf("migration to %s incomplete: %d/%d chunks re-embedded, %d not migrated: %v", newModel, len(reEmbedded), len(codeChunks), len(missing), missing)
+	}
+
+	return nil
+}
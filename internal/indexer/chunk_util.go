@@ -0,0 +1,51 @@
+package indexer
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// generateChunkID generates a unique ID for a chunk. It only depends on
+// fields every parser sets (FilePath, ChunkType, Name, StartLine), so it's
+// language-agnostic and stays stable no matter which Parser produced the
+// chunk.
+func generateChunkID(chunk *CodeChunk) string {
+	data := fmt.Sprintf("%s:%s:%s:%d", chunk.FilePath, chunk.ChunkType, chunk.Name, chunk.StartLine)
+	hash := md5.Sum([]byte(data))
+	return fmt.Sprintf("%x", hash[:8])
+}
+
+// finalizeChunk sets a chunk's ID and ContentHash. It must be called once a
+// chunk's fields are final: ContentHash is derived from Content, and ID from
+// FilePath/ChunkType/Name/StartLine (see generateChunkID); callers that
+// embed a chunk can key their cache on ContentHash to skip re-embedding text
+// that hasn't changed across indexing runs.
+func finalizeChunk(chunk *CodeChunk) {
+	chunk.ID = generateChunkID(chunk)
+	chunk.ContentHash = contentHash(chunk.Content)
+}
+
+// contentHash returns a stable hash of chunk content for cache keys.
+func contentHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// extractLines extracts lines from start to end (1-indexed), clamped to the
+// bounds of lines.
+func extractLines(lines []string, start, end int) string {
+	if start < 1 {
+		start = 1
+	}
+	if end > len(lines) {
+		end = len(lines)
+	}
+	if start > end || start > len(lines) {
+		return ""
+	}
+
+	return strings.Join(lines[start-1:end], "\n")
+}
@@ -40,7 +40,7 @@ func (c *Chunker) ChunkFile(fileInfo *FileInfo) ([]*CodeChunk, error) {
 			StartLine: 1,
 			EndLine:   strings.Count(text, "\n") + 1,
 		}
-		chunk.ID = generateChunkID(chunk)
+		finalizeChunk(chunk)
 		return []*CodeChunk{chunk}, nil
 	}
 
@@ -91,7 +91,7 @@ func (c *Chunker) chunkText(text, filePath, language string) ([]*CodeChunk, erro
 			StartLine: i + 1,
 			EndLine:   endIdx,
 		}
-		chunk.ID = generateChunkID(chunk)
+		finalizeChunk(chunk)
 		chunks = append(chunks, chunk)
 
 		// If we've reached the end, stop
@@ -136,7 +136,7 @@ func (c *Chunker) ChunkByDelimiter(text, filePath, language string, delimiters [
 					StartLine: startLine,
 					EndLine:   currentLine - 1,
 				}
-				chunk.ID = generateChunkID(chunk)
+				finalizeChunk(chunk)
 				chunks = append(chunks, chunk)
 			}
 
@@ -163,7 +163,7 @@ func (c *Chunker) ChunkByDelimiter(text, filePath, language string, delimiters [
 				StartLine: startLine,
 				EndLine:   currentLine - 1,
 			}
-			chunk.ID = generateChunkID(chunk)
+			finalizeChunk(chunk)
 			chunks = append(chunks, chunk)
 		}
 	}
@@ -0,0 +1,118 @@
+package indexer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Graph is the resolved reference graph across an indexed repository: for
+// each chunk ID, the IDs of chunks it refers to (1-hop). It's built once,
+// after every file has been parsed, from each chunk's raw References —
+// those only record symbol names at parse time, and resolving a name to a
+// concrete chunk ID needs the full symbol table that BuildGraph assembles.
+type Graph struct {
+	Edges map[string][]string `json:"edges"`
+}
+
+// BuildGraph resolves every chunk's References against the other chunks
+// from the same indexing run. It uses chunk Name (disambiguated by
+// directory when a name is ambiguous, as a stand-in for package identity)
+// instead of full go/types checking, since there's no go/packages loader
+// vendored in this tree to build a real type-checked symbol table from;
+// this is the "lightweight name-resolution pass" scoped down to plain
+// identifier matching.
+func BuildGraph(chunks []*CodeChunk) *Graph {
+	byName := make(map[string][]*CodeChunk)
+	for _, c := range chunks {
+		if c.Name != "" {
+			byName[c.Name] = append(byName[c.Name], c)
+		}
+	}
+
+	g := &Graph{Edges: make(map[string][]string)}
+
+	for _, c := range chunks {
+		if len(c.References) == 0 {
+			continue
+		}
+
+		seen := make(map[string]bool, len(c.References))
+		for _, ref := range c.References {
+			target := resolveReference(c, byName[ref.TargetName])
+			if target == nil || target.ID == c.ID || seen[target.ID] {
+				continue
+			}
+			seen[target.ID] = true
+			g.Edges[c.ID] = append(g.Edges[c.ID], target.ID)
+		}
+	}
+
+	return g
+}
+
+// resolveReference picks the best candidate for a reference made from the
+// chunk "from": one in the same directory (approximating "same package")
+// if any exists, else the first candidate found.
+func resolveReference(from *CodeChunk, candidates []*CodeChunk) *CodeChunk {
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	fromDir := filepath.Dir(from.FilePath)
+	for _, cand := range candidates {
+		if filepath.Dir(cand.FilePath) == fromDir {
+			return cand
+		}
+	}
+
+	return candidates[0]
+}
+
+// graphFileName is the sidecar BuildGraph's output is persisted under,
+// alongside the checkpoint state in .codementor.
+const graphFileName = "graph.json"
+
+// SaveGraph persists g as a graph.json sidecar under .codementor,
+// atomically (temp file plus rename), the same persistence style
+// checkpoint.go and the BM25 segment manifest use.
+func SaveGraph(g *Graph) error {
+	data, err := json.Marshal(g)
+	if err != nil {
+		return fmt.Errorf("failed to encode graph: %w", err)
+	}
+
+	if err := os.MkdirAll(".codementor", 0755); err != nil {
+		return fmt.Errorf("failed to create .codementor directory: %w", err)
+	}
+
+	path := filepath.Join(".codementor", graphFileName)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write graph: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// LoadGraph reads the graph.json sidecar written by SaveGraph.
+func LoadGraph() (*Graph, error) {
+	data, err := os.ReadFile(filepath.Join(".codementor", graphFileName))
+	if err != nil {
+		return nil, err
+	}
+
+	var g Graph
+	if err := json.Unmarshal(data, &g); err != nil {
+		return nil, fmt.Errorf("failed to parse graph: %w", err)
+	}
+	return &g, nil
+}
+
+// Neighbors returns the 1-hop chunk IDs g records for id.
+func (g *Graph) Neighbors(id string) []string {
+	if g == nil {
+		return nil
+	}
+	return g.Edges[id]
+}
@@ -14,8 +14,7 @@ import (
 type Indexer struct {
 	config   config.IndexerConfig
 	scanner  *Scanner
-	goParser *GoParser
-	chunker  *Chunker
+	registry *Registry
 }
 
 // NewIndexer creates a new indexer
@@ -23,8 +22,7 @@ func NewIndexer(cfg config.IndexerConfig) *Indexer {
 	return &Indexer{
 		config:   cfg,
 		scanner:  NewScanner(cfg),
-		goParser: NewGoParser(),
-		chunker:  NewChunker(cfg.ChunkSize, cfg.ChunkOverlap),
+		registry: NewRegistry(cfg.ChunkSize, cfg.ChunkOverlap),
 	}
 }
 
@@ -117,17 +115,27 @@ func (idx *Indexer) IndexRepository(repoPath string) (*IndexResult, error) {
 	return result, nil
 }
 
-// parseFile parses a single file and returns chunks
-func (idx *Indexer) parseFile(file *FileInfo) ([]*CodeChunk, error) {
-	lang := GetLanguage(file.Extension)
-
-	switch lang {
-	case "go":
-		return idx.goParser.Parse(file)
-	default:
-		// Use generic chunker for other languages
-		return idx.chunker.ChunkFile(file)
+// Scan scans a repository for source files without parsing them, so callers
+// like IndexResume can diff the current file tree against a checkpoint.
+func (idx *Indexer) Scan(repoPath string) ([]*FileInfo, error) {
+	absPath, err := filepath.Abs(repoPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve path: %w", err)
 	}
+	return idx.scanner.Scan(absPath)
+}
+
+// ParseFile parses a single file and returns its chunks. It is exported so
+// incremental re-indexing can re-parse just the files that changed.
+func (idx *Indexer) ParseFile(file *FileInfo) ([]*CodeChunk, error) {
+	return idx.parseFile(file)
+}
+
+// parseFile parses a single file and returns chunks, dispatching to the
+// registered Parser for its extension (or the generic chunker if none is
+// registered).
+func (idx *Indexer) parseFile(file *FileInfo) ([]*CodeChunk, error) {
+	return idx.registry.Parse(file)
 }
 
 // IndexStats returns statistics about indexed content
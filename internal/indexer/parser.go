@@ -0,0 +1,64 @@
+package indexer
+
+import "strings"
+
+// Parser extracts CodeChunks from a single file. Each implementation
+// targets one language (or a family of closely related ones, like
+// JavaScript/TypeScript); Registry dispatches to the right one by file
+// extension. GoParser is the reference implementation every other parser's
+// chunk categorization follows.
+type Parser interface {
+	// Parse extracts chunks from fileInfo.
+	Parse(fileInfo *FileInfo) ([]*CodeChunk, error)
+	// Extensions lists the lowercase file extensions (including the dot)
+	// this parser handles, e.g. []string{".py"}.
+	Extensions() []string
+	// Language names the parser's language. A parser covering several
+	// languages (JSParser, TreeSitterParser) sets a per-extension value on
+	// CodeChunk.Language instead.
+	Language() string
+}
+
+// Registry dispatches parsing to a Parser by file extension, falling back
+// to generic sliding-window chunking for extensions with no registered
+// parser.
+type Registry struct {
+	parsers  map[string]Parser
+	fallback *Chunker
+}
+
+// NewRegistry creates a Registry with the built-in parsers (Go, Python,
+// JavaScript/TypeScript, and a tree-sitter-backed parser for several more
+// languages) already registered, plus a generic chunker for extensions none
+// of them claim.
+func NewRegistry(chunkSize, chunkOverlap int) *Registry {
+	r := &Registry{
+		parsers:  make(map[string]Parser),
+		fallback: NewChunker(chunkSize, chunkOverlap),
+	}
+
+	r.Register(NewGoParser())
+	r.Register(NewPythonParser())
+	r.Register(NewJSParser())
+	r.Register(NewTreeSitterParser())
+
+	return r
+}
+
+// Register adds p to the registry, claiming every extension it reports. A
+// later Register call for an extension already claimed overrides the
+// earlier registration.
+func (r *Registry) Register(p Parser) {
+	for _, ext := range p.Extensions() {
+		r.parsers[strings.ToLower(ext)] = p
+	}
+}
+
+// Parse dispatches to the registered parser for fileInfo's extension, or
+// the generic chunker if none is registered.
+func (r *Registry) Parse(fileInfo *FileInfo) ([]*CodeChunk, error) {
+	if p, ok := r.parsers[strings.ToLower(fileInfo.Extension)]; ok {
+		return p.Parse(fileInfo)
+	}
+	return r.fallback.ChunkFile(fileInfo)
+}
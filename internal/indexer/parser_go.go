@@ -1,7 +1,6 @@
 package indexer
 
 import (
-	"crypto/md5"
 	"fmt"
 	"go/ast"
 	"go/parser"
@@ -10,7 +9,10 @@ import (
 	"strings"
 )
 
-// GoParser parses Go source files using AST
+// GoParser parses Go source files using go/ast. It's the reference
+// implementation of the Parser interface: every other language parser
+// follows the same chunk categorization (ChunkTypeFunction/Method/Struct/
+// Interface/...) that this one establishes.
 type GoParser struct{}
 
 // NewGoParser creates a new Go parser
@@ -18,6 +20,16 @@ func NewGoParser() *GoParser {
 	return &GoParser{}
 }
 
+// Extensions reports the file extensions GoParser handles.
+func (p *GoParser) Extensions() []string {
+	return []string{".go"}
+}
+
+// Language is the value Parse sets on every chunk's Language field.
+func (p *GoParser) Language() string {
+	return "go"
+}
+
 // Parse parses a Go file and extracts code chunks
 func (p *GoParser) Parse(fileInfo *FileInfo) ([]*CodeChunk, error) {
 	content, err := os.ReadFile(fileInfo.Path)
@@ -46,7 +58,7 @@ func (p *GoParser) Parse(fileInfo *FileInfo) ([]*CodeChunk, error) {
 			StartLine: fset.Position(file.Package).Line,
 			EndLine:   fset.Position(file.Package).Line,
 		}
-		pkgChunk.ID = generateChunkID(pkgChunk)
+		finalizeChunk(pkgChunk)
 		chunks = append(chunks, pkgChunk)
 	}
 
@@ -97,7 +109,7 @@ func (p *GoParser) extractImports(file *ast.File, fset *token.FileSet, lines []s
 			EndLine:   endLine,
 			Imports:   importNames,
 		}
-		chunk.ID = generateChunkID(chunk)
+		finalizeChunk(chunk)
 		chunks = append(chunks, chunk)
 	}
 
@@ -128,6 +140,17 @@ func (p *GoParser) parseFuncDecl(fn *ast.FuncDecl, fset *token.FileSet, lines []
 
 	content := extractLines(lines, startLine, endLine)
 
+	var refs []Reference
+	if fn.Type.Params != nil {
+		refs = append(refs, fieldListTypeReferences(fn.Type.Params)...)
+	}
+	if fn.Type.Results != nil {
+		refs = append(refs, fieldListTypeReferences(fn.Type.Results)...)
+	}
+	if fn.Body != nil {
+		refs = append(refs, collectCallReferences(fn.Body)...)
+	}
+
 	chunk := &CodeChunk{
 		FilePath:   filePath,
 		Language:   "go",
@@ -139,8 +162,9 @@ func (p *GoParser) parseFuncDecl(fn *ast.FuncDecl, fset *token.FileSet, lines []
 		EndLine:    endLine,
 		DocComment: strings.TrimSpace(docComment),
 		ParentName: parentName,
+		References: dedupeReferences(refs),
 	}
-	chunk.ID = generateChunkID(chunk)
+	finalizeChunk(chunk)
 
 	return chunk
 }
@@ -175,6 +199,7 @@ func (p *GoParser) parseTypeSpec(spec *ast.TypeSpec, decl *ast.GenDecl, fset *to
 
 	var chunkType ChunkType
 	var signature string
+	var refs []Reference
 
 	switch t := spec.Type.(type) {
 	case *ast.StructType:
@@ -183,6 +208,7 @@ func (p *GoParser) parseTypeSpec(spec *ast.TypeSpec, decl *ast.GenDecl, fset *to
 		// Include field count in metadata
 		if t.Fields != nil {
 			signature = fmt.Sprintf("type %s struct { %d fields }", spec.Name.Name, len(t.Fields.List))
+			refs = fieldListTypeReferences(t.Fields)
 		}
 
 	case *ast.InterfaceType:
@@ -190,12 +216,14 @@ func (p *GoParser) parseTypeSpec(spec *ast.TypeSpec, decl *ast.GenDecl, fset *to
 		signature = fmt.Sprintf("type %s interface", spec.Name.Name)
 		if t.Methods != nil {
 			signature = fmt.Sprintf("type %s interface { %d methods }", spec.Name.Name, len(t.Methods.List))
+			refs = fieldListTypeReferences(t.Methods)
 		}
 
 	default:
 		// Type alias or other type definitions
 		chunkType = ChunkTypeGeneric
 		signature = fmt.Sprintf("type %s", spec.Name.Name)
+		refs = typeReferences(spec.Type)
 	}
 
 	// Extract doc comment
@@ -218,8 +246,9 @@ func (p *GoParser) parseTypeSpec(spec *ast.TypeSpec, decl *ast.GenDecl, fset *to
 		StartLine:  startLine,
 		EndLine:    endLine,
 		DocComment: strings.TrimSpace(docComment),
+		References: dedupeReferences(refs),
 	}
-	chunk.ID = generateChunkID(chunk)
+	finalizeChunk(chunk)
 
 	return chunk
 }
@@ -272,7 +301,7 @@ func (p *GoParser) parseValueSpec(spec *ast.ValueSpec, decl *ast.GenDecl, fset *
 		EndLine:    endLine,
 		DocComment: strings.TrimSpace(docComment),
 	}
-	chunk.ID = generateChunkID(chunk)
+	finalizeChunk(chunk)
 
 	return chunk
 }
@@ -288,7 +317,7 @@ func (p *GoParser) fallbackParse(fileInfo *FileInfo, content []byte) ([]*CodeChu
 		StartLine: 1,
 		EndLine:   strings.Count(string(content), "\n") + 1,
 	}
-	chunk.ID = generateChunkID(chunk)
+	finalizeChunk(chunk)
 
 	return []*CodeChunk{chunk}, nil
 }
@@ -396,25 +425,115 @@ func exprToString(expr ast.Expr) string {
 	}
 }
 
-// extractLines extracts lines from start to end (1-indexed)
-func extractLines(lines []string, start, end int) string {
-	if start < 1 {
-		start = 1
-	}
-	if end > len(lines) {
-		end = len(lines)
-	}
-	if start > end || start > len(lines) {
-		return ""
+// collectCallReferences walks node and records every function/method call
+// it makes as a Reference, so retrieval can later expand a chunk to its
+// callees. There's no go/types checker wired in here (that needs a full
+// package-loading setup this tree has no dependency to vendor), so a call's
+// target is recorded by name only; BuildGraph resolves names to chunk IDs
+// in a later pass once every file has been parsed.
+func collectCallReferences(node ast.Node) []Reference {
+	var refs []Reference
+	callTarget := make(map[*ast.SelectorExpr]bool)
+
+	ast.Inspect(node, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+
+		switch fn := call.Fun.(type) {
+		case *ast.Ident:
+			refs = append(refs, Reference{TargetName: fn.Name, Kind: "call"})
+		case *ast.SelectorExpr:
+			callTarget[fn] = true
+			if pkg, ok := fn.X.(*ast.Ident); ok {
+				refs = append(refs, Reference{TargetName: fn.Sel.Name, TargetPkg: pkg.Name, Kind: "call"})
+			} else {
+				refs = append(refs, Reference{TargetName: fn.Sel.Name, Kind: "call"})
+			}
+		}
+
+		return true
+	})
+
+	// A second pass for plain field/method selector access (x.Field) that
+	// wasn't already recorded as part of a call above.
+	ast.Inspect(node, func(n ast.Node) bool {
+		sel, ok := n.(*ast.SelectorExpr)
+		if !ok || callTarget[sel] {
+			return true
+		}
+		if pkg, ok := sel.X.(*ast.Ident); ok {
+			refs = append(refs, Reference{TargetName: sel.Sel.Name, TargetPkg: pkg.Name, Kind: "select"})
+		}
+		return true
+	})
+
+	return refs
+}
+
+// fieldListTypeReferences collects type references from every field in fl
+// (a function's params/results, or a struct's fields, or an interface's
+// method set).
+func fieldListTypeReferences(fl *ast.FieldList) []Reference {
+	var refs []Reference
+	for _, field := range fl.List {
+		refs = append(refs, typeReferences(field.Type)...)
 	}
+	return refs
+}
 
-	return strings.Join(lines[start-1:end], "\n")
+// goPredeclaredTypes are the builtin type names that aren't worth recording
+// as references, since they can never resolve to another chunk.
+var goPredeclaredTypes = map[string]bool{
+	"bool": true, "string": true, "error": true, "any": true,
+	"int": true, "int8": true, "int16": true, "int32": true, "int64": true,
+	"uint": true, "uint8": true, "uint16": true, "uint32": true, "uint64": true, "uintptr": true,
+	"float32": true, "float64": true, "complex64": true, "complex128": true,
+	"byte": true, "rune": true,
 }
 
-// generateChunkID generates a unique ID for a chunk
-func generateChunkID(chunk *CodeChunk) string {
-	data := fmt.Sprintf("%s:%s:%s:%d", chunk.FilePath, chunk.ChunkType, chunk.Name, chunk.StartLine)
-	hash := md5.Sum([]byte(data))
-	return fmt.Sprintf("%x", hash[:8])
+// typeReferences extracts the named type(s) referenced by a type
+// expression, unwrapping pointers/slices/maps to find the underlying named
+// type(s).
+func typeReferences(expr ast.Expr) []Reference {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		if goPredeclaredTypes[t.Name] {
+			return nil
+		}
+		return []Reference{{TargetName: t.Name, Kind: "type"}}
+	case *ast.StarExpr:
+		return typeReferences(t.X)
+	case *ast.SelectorExpr:
+		if pkg, ok := t.X.(*ast.Ident); ok {
+			return []Reference{{TargetName: t.Sel.Name, TargetPkg: pkg.Name, Kind: "type"}}
+		}
+		return nil
+	case *ast.ArrayType:
+		return typeReferences(t.Elt)
+	case *ast.MapType:
+		return append(typeReferences(t.Key), typeReferences(t.Value)...)
+	default:
+		return nil
+	}
 }
 
+// dedupeReferences removes duplicate (TargetName, TargetPkg, Kind) entries,
+// preserving first-seen order.
+func dedupeReferences(refs []Reference) []Reference {
+	if len(refs) == 0 {
+		return nil
+	}
+
+	seen := make(map[Reference]bool, len(refs))
+	out := make([]Reference, 0, len(refs))
+	for _, r := range refs {
+		if seen[r] {
+			continue
+		}
+		seen[r] = true
+		out = append(out, r)
+	}
+	return out
+}
@@ -0,0 +1,191 @@
+package indexer
+
+import (
+	"os"
+	"regexp"
+	"strings"
+)
+
+// JSParser chunks JavaScript and TypeScript source using brace-matching
+// regexes rather than a real AST — there's no tree-sitter or Babel binding
+// vendored in this tree (and no dependency management to add one). It
+// recognizes top-level `function`/arrow declarations and `class` bodies
+// (with their methods), which covers the overwhelming majority of
+// real-world JS/TS code. Code outside the recognized declarations is not
+// chunked; a file in which none are recognized becomes one whole-file chunk,
+// the same way an unparseable Go file does.
+type JSParser struct{}
+
+// NewJSParser creates a new JavaScript/TypeScript parser.
+func NewJSParser() *JSParser {
+	return &JSParser{}
+}
+
+// Extensions reports the file extensions JSParser handles.
+func (p *JSParser) Extensions() []string {
+	return []string{".js", ".jsx", ".ts", ".tsx"}
+}
+
+// Language reports "javascript". Parse itself sets each chunk's Language
+// from GetLanguage(fileInfo.Extension), so chunks from .ts/.tsx files carry
+// "typescript".
+func (p *JSParser) Language() string {
+	return "javascript"
+}
+
+var (
+	jsClassRegex    = regexp.MustCompile(`^\s*(?:export\s+)?(?:default\s+)?class\s+([A-Za-z_$][A-Za-z0-9_$]*)`)
+	jsFunctionRegex = regexp.MustCompile(`^\s*(?:export\s+)?(?:default\s+)?(?:async\s+)?function\s*\*?\s+([A-Za-z_$][A-Za-z0-9_$]*)\s*\(`)
+	jsArrowRegex    = regexp.MustCompile(`^\s*(?:export\s+)?(?:const|let|var)\s+([A-Za-z_$][A-Za-z0-9_$]*)\s*=\s*(?:async\s+)?\(?[^=]*\)?\s*=>\s*\{?\s*$`)
+	jsMethodRegex   = regexp.MustCompile(`^\s*(?:public\s+|private\s+|protected\s+|static\s+|async\s+|get\s+|set\s+)*([A-Za-z_$][A-Za-z0-9_$]*)\s*\([^)]*\)\s*(?::\s*[^{]+)?\{\s*$`)
+	jsKeywords      = map[string]bool{
+		"if": true, "for": true, "while": true, "switch": true, "catch": true,
+		"function": true, "return": true, "else": true,
+	}
+)
+
+// Parse extracts chunks from a JS/TS file.
+func (p *JSParser) Parse(fileInfo *FileInfo) ([]*CodeChunk, error) {
+	content, err := os.ReadFile(fileInfo.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := strings.Split(string(content), "\n")
+	language := GetLanguage(fileInfo.Extension)
+
+	claimed := make([]bool, len(lines)) // lines already inside a class body
+	var chunks []*CodeChunk
+
+	for i, line := range lines {
+		m := jsClassRegex.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		name := m[1]
+		endLine := braceEnd(lines, i)
+
+		for l := i; l <= endLine && l < len(claimed); l++ {
+			claimed[l] = true
+		}
+
+		chunk := &CodeChunk{
+			FilePath:  fileInfo.RelPath,
+			Language:  language,
+			ChunkType: ChunkTypeStruct,
+			Name:      name,
+			Signature: strings.TrimSpace(line),
+			Content:   extractLines(lines, i+1, endLine+1),
+			StartLine: i + 1,
+			EndLine:   endLine + 1,
+		}
+		finalizeChunk(chunk)
+		chunks = append(chunks, chunk)
+
+		for l := i + 1; l < endLine; l++ {
+			mm := jsMethodRegex.FindStringSubmatch(lines[l])
+			if mm == nil || jsKeywords[mm[1]] {
+				continue
+			}
+			methodEnd := braceEnd(lines, l)
+			if methodEnd > endLine {
+				methodEnd = endLine
+			}
+
+			method := &CodeChunk{
+				FilePath:   fileInfo.RelPath,
+				Language:   language,
+				ChunkType:  ChunkTypeMethod,
+				Name:       mm[1],
+				Signature:  strings.TrimSpace(lines[l]),
+				Content:    extractLines(lines, l+1, methodEnd+1),
+				StartLine:  l + 1,
+				EndLine:    methodEnd + 1,
+				ParentName: name,
+			}
+			finalizeChunk(method)
+			chunks = append(chunks, method)
+		}
+	}
+
+	for i, line := range lines {
+		if claimed[i] {
+			continue
+		}
+
+		var name string
+		if m := jsFunctionRegex.FindStringSubmatch(line); m != nil {
+			name = m[1]
+		} else if m := jsArrowRegex.FindStringSubmatch(line); m != nil {
+			name = m[1]
+		} else {
+			continue
+		}
+
+		endLine := braceEnd(lines, i)
+
+		chunk := &CodeChunk{
+			FilePath:  fileInfo.RelPath,
+			Language:  language,
+			ChunkType: ChunkTypeFunction,
+			Name:      name,
+			Signature: strings.TrimSpace(line),
+			Content:   extractLines(lines, i+1, endLine+1),
+			StartLine: i + 1,
+			EndLine:   endLine + 1,
+		}
+		finalizeChunk(chunk)
+		chunks = append(chunks, chunk)
+	}
+
+	if len(chunks) == 0 {
+		return p.fallbackParse(fileInfo, lines, language)
+	}
+
+	return chunks, nil
+}
+
+// braceEnd returns the 0-indexed line on which the first '{' at or after
+// startLine is closed, counting braces across the rest of the file. String
+// and comment contents aren't excluded from the count, so a brace character
+// inside a string literal can throw this off — an accepted limitation of a
+// regex-based chunker with no real tokenizer behind it. If no '{' is found
+// at all, startLine is returned (the declaration is treated as single-line,
+// e.g. a braceless arrow function).
+func braceEnd(lines []string, startLine int) int {
+	depth := 0
+	seenOpen := false
+
+	for i := startLine; i < len(lines); i++ {
+		for _, r := range lines[i] {
+			switch r {
+			case '{':
+				depth++
+				seenOpen = true
+			case '}':
+				depth--
+			}
+		}
+		if seenOpen && depth <= 0 {
+			return i
+		}
+	}
+
+	return startLine
+}
+
+// fallbackParse handles files with no recognizable declarations by
+// returning the whole file as one chunk.
+func (p *JSParser) fallbackParse(fileInfo *FileInfo, lines []string, language string) ([]*CodeChunk, error) {
+	chunk := &CodeChunk{
+		FilePath:  fileInfo.RelPath,
+		Language:  language,
+		ChunkType: ChunkTypeFile,
+		Name:      fileInfo.RelPath,
+		Content:   strings.Join(lines, "\n"),
+		StartLine: 1,
+		EndLine:   len(lines),
+	}
+	finalizeChunk(chunk)
+	return []*CodeChunk{chunk}, nil
+}
@@ -0,0 +1,214 @@
+package indexer
+
+import (
+	"os"
+	"regexp"
+	"strings"
+)
+
+// PythonParser chunks Python source by its indentation structure instead of
+// a real AST: there's no Go Python-AST library vendored in this tree (and no
+// dependency management to add one), so this takes the same tokenize-style
+// approach CPython's own `tokenize` module exposes — scan for `def`/`class`
+// lines, and use the indentation of the following lines to find where each
+// block ends.
+type PythonParser struct{}
+
+// NewPythonParser creates a new Python parser.
+func NewPythonParser() *PythonParser {
+	return &PythonParser{}
+}
+
+// Extensions reports the file extensions PythonParser handles.
+func (p *PythonParser) Extensions() []string {
+	return []string{".py"}
+}
+
+// Language is the value Parse sets on every chunk's Language field.
+func (p *PythonParser) Language() string {
+	return "python"
+}
+
+var pyDefRegex = regexp.MustCompile(`^(\s*)(def|class)\s+([A-Za-z_][A-Za-z0-9_]*)`)
+
+// pyBlock is one def/class line found during the scan, before its end line
+// and parent have been resolved.
+type pyBlock struct {
+	line   int // 0-indexed line of the def/class keyword
+	indent int
+	kind   string // "def" or "class"
+	name   string
+}
+
+// Parse extracts chunks from a Python file.
+func (p *PythonParser) Parse(fileInfo *FileInfo) ([]*CodeChunk, error) {
+	content, err := os.ReadFile(fileInfo.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := strings.Split(string(content), "\n")
+
+	var blocks []pyBlock
+	for i, line := range lines {
+		m := pyDefRegex.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		blocks = append(blocks, pyBlock{
+			line:   i,
+			indent: len(m[1]),
+			kind:   m[2],
+			name:   m[3],
+		})
+	}
+
+	if len(blocks) == 0 {
+		return p.fallbackParse(fileInfo, lines)
+	}
+
+	var chunks []*CodeChunk
+	var stack []pyBlock
+
+	for bi, b := range blocks {
+		for len(stack) > 0 && stack[len(stack)-1].indent >= b.indent {
+			stack = stack[:len(stack)-1]
+		}
+
+		endLine := p.blockEnd(lines, b, blocks[bi+1:])
+
+		chunkType := ChunkTypeFunction
+		var parentName string
+		if b.kind == "class" {
+			chunkType = ChunkTypeStruct
+		} else if len(stack) > 0 && stack[len(stack)-1].kind == "class" {
+			chunkType = ChunkTypeMethod
+			parentName = stack[len(stack)-1].name
+		}
+
+		content := extractLines(lines, b.line+1, endLine)
+
+		chunk := &CodeChunk{
+			FilePath:   fileInfo.RelPath,
+			Language:   p.Language(),
+			ChunkType:  chunkType,
+			Name:       b.name,
+			Signature:  p.signature(lines, b.line),
+			Content:    content,
+			StartLine:  b.line + 1,
+			EndLine:    endLine,
+			DocComment: p.docstring(lines, b.line),
+			ParentName: parentName,
+		}
+		finalizeChunk(chunk)
+		chunks = append(chunks, chunk)
+
+		stack = append(stack, b)
+	}
+
+	return chunks, nil
+}
+
+// blockEnd finds the last line (1-indexed) belonging to the block starting
+// at b: everything up to (but not including) the next block at an
+// indentation <= b.indent, trimmed of trailing blank lines.
+func (p *PythonParser) blockEnd(lines []string, b pyBlock, rest []pyBlock) int {
+	end := len(lines)
+	for _, next := range rest {
+		if next.indent <= b.indent {
+			end = next.line
+			break
+		}
+	}
+
+	for end > b.line+1 && strings.TrimSpace(lines[end-1]) == "" {
+		end--
+	}
+
+	return end
+}
+
+// signature joins the def/class line with however many following lines its
+// parameter list spans, so a multi-line signature still renders as one
+// logical line.
+func (p *PythonParser) signature(lines []string, startLine int) string {
+	var sb strings.Builder
+	depth := 0
+
+	for i := startLine; i < len(lines); i++ {
+		line := lines[i]
+		if sb.Len() > 0 {
+			sb.WriteString(" ")
+		}
+		sb.WriteString(strings.TrimSpace(line))
+
+		for _, r := range line {
+			switch r {
+			case '(':
+				depth++
+			case ')':
+				depth--
+			}
+		}
+
+		if depth <= 0 && strings.HasSuffix(strings.TrimSpace(line), ":") {
+			break
+		}
+	}
+
+	return sb.String()
+}
+
+// docstring extracts a triple-quoted docstring immediately following a
+// def/class line, if there is one.
+func (p *PythonParser) docstring(lines []string, defLine int) string {
+	for i := defLine + 1; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		if trimmed == "" {
+			continue
+		}
+
+		for _, quote := range []string{`"""`, `'''`} {
+			if !strings.HasPrefix(trimmed, quote) {
+				continue
+			}
+			body := strings.TrimPrefix(trimmed, quote)
+			if end := strings.Index(body, quote); end >= 0 {
+				return strings.TrimSpace(body[:end])
+			}
+
+			var sb strings.Builder
+			sb.WriteString(body)
+			for j := i + 1; j < len(lines); j++ {
+				if end := strings.Index(lines[j], quote); end >= 0 {
+					sb.WriteString("\n")
+					sb.WriteString(lines[j][:end])
+					return strings.TrimSpace(sb.String())
+				}
+				sb.WriteString("\n")
+				sb.WriteString(lines[j])
+			}
+			return strings.TrimSpace(sb.String())
+		}
+
+		return ""
+	}
+
+	return ""
+}
+
+// fallbackParse handles files with no recognizable def/class lines (e.g.
+// plain scripts) by returning the whole file as one chunk.
+func (p *PythonParser) fallbackParse(fileInfo *FileInfo, lines []string) ([]*CodeChunk, error) {
+	chunk := &CodeChunk{
+		FilePath:  fileInfo.RelPath,
+		Language:  p.Language(),
+		ChunkType: ChunkTypeFile,
+		Name:      fileInfo.RelPath,
+		Content:   strings.Join(lines, "\n"),
+		StartLine: 1,
+		EndLine:   len(lines),
+	}
+	finalizeChunk(chunk)
+	return []*CodeChunk{chunk}, nil
+}
@@ -0,0 +1,275 @@
+package indexer
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// TreeSitterParser is the fallback for languages with no hand-written
+// Parser in this package (Rust, Java, C/C++, Ruby, PHP, Swift, Kotlin,
+// Scala): it shells out to the `tree-sitter` CLI, which ships prebuilt
+// grammars for all of them, rather than embedding go-tree-sitter bindings —
+// this tree has no dependency management to vendor a cgo-based binding
+// into, the same constraint PythonParser and JSParser's doc comments note
+// for their own languages. `tree-sitter parse` is a common enough
+// dev-machine binary that shelling out to it via os/exec (stdlib only) is a
+// reasonable middle ground. If the CLI isn't on PATH, Parse falls back to
+// the same sliding-window chunker Registry uses for extensions with no
+// registered parser, and logs a warning (see Parse).
+//
+// Note on scope: Parser/Registry (parser.go) is this repo's per-language
+// dispatch mechanism — Registry.Parse already plays the role a standalone
+// LanguageChunker interface wired into Chunker.ChunkFile would. This parser
+// extends that existing mechanism rather than adding a second one; it does
+// not vendor github.com/smacker/go-tree-sitter, since nothing in this tree
+// is set up to vendor cgo dependencies at all.
+type TreeSitterParser struct {
+	fallback *Chunker
+
+	// warnMissingCLI ensures the "no tree-sitter on PATH" warning is printed
+	// at most once per TreeSitterParser (each Indexer's Registry builds its
+	// own), even though Parse runs concurrently across many files during
+	// indexing and would otherwise print it once per file in this parser's
+	// language set.
+	warnMissingCLI sync.Once
+}
+
+// NewTreeSitterParser creates a new tree-sitter-backed parser. Its fallback
+// chunker uses the same defaults as config.DefaultConfig's indexer section,
+// since a TreeSitterParser can be constructed standalone (e.g. in tests)
+// without an IndexerConfig to read sizes from.
+func NewTreeSitterParser() *TreeSitterParser {
+	return &TreeSitterParser{fallback: NewChunker(1000, 200)}
+}
+
+// Extensions reports the file extensions TreeSitterParser handles.
+func (p *TreeSitterParser) Extensions() []string {
+	return []string{".rs", ".java", ".c", ".h", ".hpp", ".cpp", ".cc", ".cxx", ".rb", ".php", ".swift", ".kt", ".scala"}
+}
+
+// Language is the value Parse sets on every chunk's Language field when it
+// can't determine a more specific one; Parse always overrides it with
+// GetLanguage(fileInfo.Extension).
+func (p *TreeSitterParser) Language() string {
+	return "unknown"
+}
+
+// treeSitterNodeRegex matches one line of `tree-sitter parse`'s s-expression
+// dump, e.g. `  (function_item [12, 0] - [15, 1]`.
+var treeSitterNodeRegex = regexp.MustCompile(`\((\w+) \[(\d+), \d+\] - \[(\d+), \d+\]`)
+
+// treeSitterChunkKinds maps tree-sitter node type names (these vary a bit
+// per grammar, hence the long list) to the CodeChunk categorization the
+// rest of the indexer expects. class_declaration/class_definition default
+// to ChunkTypeStruct here; classLanguages below upgrades that to
+// ChunkTypeClass for the languages whose own vocabulary calls it a class.
+var treeSitterChunkKinds = map[string]ChunkType{
+	"function_item":         ChunkTypeFunction,
+	"function_definition":   ChunkTypeFunction,
+	"function_declaration":  ChunkTypeFunction,
+	"method_declaration":    ChunkTypeMethod,
+	"method_definition":     ChunkTypeMethod,
+	"impl_item":             ChunkTypeStruct,
+	"class_declaration":     ChunkTypeStruct,
+	"class_definition":      ChunkTypeStruct,
+	"struct_item":           ChunkTypeStruct,
+	"interface_declaration": ChunkTypeInterface,
+}
+
+// classLanguages are the languages whose class_declaration/class_definition
+// nodes should be categorized as ChunkTypeClass rather than ChunkTypeStruct
+// — every language in Extensions() with a "class" keyword except C++.
+// Rust and C/C++ fall through to ChunkTypeStruct, matching the struct/impl
+// vocabulary those grammars actually use.
+var classLanguages = map[string]bool{
+	"java": true, "ruby": true, "php": true, "swift": true, "kotlin": true, "scala": true,
+}
+
+// lineCommentPrefixes lists each language's single-line comment marker(s),
+// used by docComment to find a node's preceding comment block. Languages not
+// listed (e.g. ones with only block comments in common use) simply never
+// match, so docComment returns "" for them.
+var lineCommentPrefixes = map[string][]string{
+	"java": {"//"}, "rust": {"//"}, "c": {"//"}, "cpp": {"//"}, "c_header": {"//"},
+	"swift": {"//"}, "kotlin": {"//"}, "scala": {"//"}, "ruby": {"#"}, "php": {"//", "#"},
+}
+
+var treeSitterNameRegex = regexp.MustCompile(`\b(?:fn|func|function|def|class|struct|interface|impl)\s+([A-Za-z_][A-Za-z0-9_]*)`)
+
+// tsNode is one matched s-expression node, before Name/Signature/DocComment/
+// ParentName have been resolved from the source lines.
+type tsNode struct {
+	chunkType ChunkType
+	startRow  int // 0-indexed
+	endRow    int // 0-indexed, inclusive
+}
+
+// Parse shells out to `tree-sitter parse` and extracts chunks from its
+// s-expression output; if the CLI isn't available or the invocation fails,
+// it falls back to generic sliding-window chunking and prints a warning, so
+// degraded chunking for a language is visible instead of silent.
+func (p *TreeSitterParser) Parse(fileInfo *FileInfo) ([]*CodeChunk, error) {
+	language := GetLanguage(fileInfo.Extension)
+
+	if _, err := exec.LookPath("tree-sitter"); err != nil {
+		p.warnMissingCLI.Do(func() {
+			fmt.Printf("⚠️  tree-sitter CLI not found on PATH; %s and other tree-sitter-only languages will fall back to generic sliding-window chunking\n", language)
+		})
+		return p.fallback.ChunkFile(fileInfo)
+	}
+
+	out, err := exec.Command("tree-sitter", "parse", fileInfo.Path).Output()
+	if err != nil {
+		fmt.Printf("⚠️  tree-sitter parse failed for %s, falling back to generic chunking: %v\n", fileInfo.RelPath, err)
+		return p.fallback.ChunkFile(fileInfo)
+	}
+
+	content, err := os.ReadFile(fileInfo.Path)
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(string(content), "\n")
+
+	var nodes []tsNode
+	for _, dumpLine := range strings.Split(string(out), "\n") {
+		m := treeSitterNodeRegex.FindStringSubmatch(dumpLine)
+		if m == nil {
+			continue
+		}
+
+		chunkType, ok := treeSitterChunkKinds[m[1]]
+		if !ok {
+			continue
+		}
+		if (m[1] == "class_declaration" || m[1] == "class_definition") && classLanguages[language] {
+			chunkType = ChunkTypeClass
+		}
+
+		startRow, _ := strconv.Atoi(m[2])
+		endRow, _ := strconv.Atoi(m[3])
+		nodes = append(nodes, tsNode{chunkType: chunkType, startRow: startRow, endRow: endRow})
+	}
+
+	// containers tracks struct/class/interface/impl nodes already emitted,
+	// innermost first, so a method nested inside one picks up its
+	// ParentName — the same containing-span approach PythonParser uses via
+	// its indent stack, adapted to tree-sitter's row ranges since there's no
+	// indentation to key off of here.
+	var containers []*CodeChunk
+	var chunks []*CodeChunk
+
+	for _, n := range nodes {
+		for len(containers) > 0 && containers[len(containers)-1].EndLine-1 < n.startRow {
+			containers = containers[:len(containers)-1]
+		}
+
+		name := fileInfo.RelPath
+		if n.startRow < len(lines) {
+			if nm := treeSitterNameRegex.FindStringSubmatch(lines[n.startRow]); nm != nil {
+				name = nm[1]
+			}
+		}
+
+		// Rust's impl methods and some grammars' class methods surface as
+		// function_item/function_definition rather than method_declaration,
+		// so ParentName is set from the enclosing container for any
+		// function/method node, not just ones already typed as a method.
+		var parentName string
+		if (n.chunkType == ChunkTypeMethod || n.chunkType == ChunkTypeFunction) && len(containers) > 0 {
+			parentName = containers[len(containers)-1].Name
+		}
+
+		chunk := &CodeChunk{
+			FilePath:   fileInfo.RelPath,
+			Language:   language,
+			ChunkType:  n.chunkType,
+			Name:       name,
+			Signature:  strings.TrimSpace(lines[clampRow(n.startRow, len(lines))]),
+			Content:    extractLines(lines, n.startRow+1, n.endRow+1),
+			StartLine:  n.startRow + 1,
+			EndLine:    n.endRow + 1,
+			DocComment: docComment(lines, n.startRow, language),
+			ParentName: parentName,
+		}
+		finalizeChunk(chunk)
+		chunks = append(chunks, chunk)
+
+		if n.chunkType == ChunkTypeStruct || n.chunkType == ChunkTypeClass || n.chunkType == ChunkTypeInterface {
+			containers = append(containers, chunk)
+		}
+	}
+
+	if len(chunks) == 0 {
+		return p.fallback.ChunkFile(fileInfo)
+	}
+
+	return chunks, nil
+}
+
+// clampRow keeps row within [0, numLines-1], in case a node's reported
+// startRow falls on or past the file's last line (tree-sitter's row count
+// and strings.Split's line count can differ by one on a trailing newline).
+func clampRow(row, numLines int) int {
+	if row >= numLines {
+		return numLines - 1
+	}
+	if row < 0 {
+		return 0
+	}
+	return row
+}
+
+// tsAnnotationRegex matches a decorator/annotation/attribute line sitting
+// between a doc comment and the declaration it documents — Java/Kotlin/
+// Scala/Swift's "@Foo", and PHP's "#[Foo]" attribute syntax. These are
+// skipped rather than treated as "no comment here", so a leading "//" block
+// above one or more annotation lines is still picked up.
+var tsAnnotationRegex = regexp.MustCompile(`^(@[A-Za-z_]|#\[)`)
+
+// docComment walks backward from a node's 0-indexed startRow over
+// contiguous single-line comments in language's comment style (skipping
+// over any annotation/attribute lines in between), returning them joined
+// back into their original order. Languages with no entry in
+// lineCommentPrefixes (or a node with no preceding comment) yield "".
+func docComment(lines []string, startRow int, language string) string {
+	prefixes, ok := lineCommentPrefixes[language]
+	if !ok {
+		return ""
+	}
+
+	var commentLines []string
+	for i := startRow - 1; i >= 0; i-- {
+		trimmed := strings.TrimSpace(lines[i])
+		if trimmed == "" {
+			break
+		}
+		if tsAnnotationRegex.MatchString(trimmed) {
+			continue
+		}
+
+		matched := false
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(trimmed, prefix) {
+				commentLines = append(commentLines, strings.TrimSpace(strings.TrimPrefix(trimmed, prefix)))
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			break
+		}
+	}
+
+	// commentLines was built walking upward, so reverse it back into
+	// top-to-bottom order before joining.
+	for i, j := 0, len(commentLines)-1; i < j; i, j = i+1, j-1 {
+		commentLines[i], commentLines[j] = commentLines[j], commentLines[i]
+	}
+
+	return strings.Join(commentLines, "\n")
+}
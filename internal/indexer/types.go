@@ -7,6 +7,7 @@ const (
 	ChunkTypeFunction  ChunkType = "function"
 	ChunkTypeMethod    ChunkType = "method"
 	ChunkTypeStruct    ChunkType = "struct"
+	ChunkTypeClass     ChunkType = "class" // Like ChunkTypeStruct, but for languages whose grammar calls it a class (Java, Ruby, PHP, Swift, Kotlin, Scala)
 	ChunkTypeInterface ChunkType = "interface"
 	ChunkTypeConstant  ChunkType = "constant"
 	ChunkTypeVariable  ChunkType = "variable"
@@ -24,14 +25,27 @@ type CodeChunk struct {
 	FilePath    string            `json:"file_path"`
 	Language    string            `json:"language"`
 	ChunkType   ChunkType         `json:"chunk_type"`
-	Name        string            `json:"name"`        // Function/struct/variable name
-	Signature   string            `json:"signature"`   // Function signature, struct definition
+	Name        string            `json:"name"`          // Function/struct/variable name
+	Signature   string            `json:"signature"`     // Function signature, struct definition
 	StartLine   int               `json:"start_line"`
 	EndLine     int               `json:"end_line"`
-	DocComment  string            `json:"doc_comment"` // Documentation comment
-	ParentName  string            `json:"parent_name"` // For methods: the struct name
-	Imports     []string          `json:"imports"`     // Related imports
-	Metadata    map[string]string `json:"metadata"`    // Additional metadata
+	DocComment  string            `json:"doc_comment"`   // Documentation comment
+	ParentName  string            `json:"parent_name"`   // For methods: the struct name
+	Imports     []string          `json:"imports"`       // Related imports
+	Metadata    map[string]string `json:"metadata"`      // Additional metadata
+	ContentHash string            `json:"content_hash"`  // sha256(Content), stable across re-parses
+	References  []Reference       `json:"references,omitempty"` // Calls/selectors/types this chunk refers to, resolved by BuildGraph
+}
+
+// Reference is a symbol a chunk refers to: a function call, a field/method
+// selector access, or a type reference. TargetName/TargetPkg are recorded
+// at parse time from the raw AST (so TargetPkg is an import alias, not a
+// fully-resolved package path); BuildGraph resolves TargetName against the
+// other chunks from the same indexing run to turn these into concrete edges.
+type Reference struct {
+	TargetName string `json:"target_name"`
+	TargetPkg  string `json:"target_pkg,omitempty"`
+	Kind       string `json:"kind"` // "call", "select", or "type"
 }
 
 // Repository represents an indexed code repository
@@ -0,0 +1,211 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Event describes a file that changed while a Watcher was running. Chunks is
+// nil and Removed is true when the file was deleted (or renamed away); the
+// caller is expected to invalidate whatever chunk IDs it previously recorded
+// for Path.
+type Event struct {
+	Path    string
+	Chunks  []*CodeChunk
+	Removed bool
+}
+
+// Watcher watches a repository root for file changes and re-parses only the
+// file that changed, so a long-running process can keep its indexes fresh
+// without re-scanning the whole tree on every save.
+type Watcher struct {
+	idx      *Indexer
+	root     string
+	debounce time.Duration
+	fsw      *fsnotify.Watcher
+
+	mu     sync.Mutex
+	timers map[string]*time.Timer
+}
+
+// NewWatcher creates a Watcher rooted at repoPath. debounce controls how
+// long to wait after a file's last write event before re-parsing it, so a
+// burst of saves (autosave, gofmt-on-save) only triggers one re-parse; a
+// debounce <= 0 defaults to 250ms.
+func NewWatcher(idx *Indexer, repoPath string, debounce time.Duration) (*Watcher, error) {
+	absPath, err := filepath.Abs(repoPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve path: %w", err)
+	}
+
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create filesystem watcher: %w", err)
+	}
+
+	if debounce <= 0 {
+		debounce = 250 * time.Millisecond
+	}
+
+	w := &Watcher{
+		idx:      idx,
+		root:     absPath,
+		debounce: debounce,
+		fsw:      fsw,
+		timers:   make(map[string]*time.Timer),
+	}
+
+	if err := w.watchDirs(absPath); err != nil {
+		fsw.Close()
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// watchDirs recursively registers every non-ignored directory under root.
+// fsnotify only watches the directories it's explicitly told about, not
+// whole subtrees, so newly created directories are added as they appear (see
+// Run's handling of fsnotify.Create events).
+func (w *Watcher) watchDirs(root string) error {
+	ignoreMap := make(map[string]bool)
+	for _, dir := range w.idx.config.IgnoreDirs {
+		ignoreMap[dir] = true
+	}
+
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		if ignoreMap[info.Name()] {
+			return filepath.SkipDir
+		}
+		return w.fsw.Add(path)
+	})
+}
+
+// Run watches for file events until ctx is cancelled, calling onEvent once
+// per debounced change. onEvent is invoked from a per-file timer goroutine,
+// so callers that mutate shared state (a BM25 index, a vector store) must
+// synchronize it themselves.
+func (w *Watcher) Run(ctx context.Context, onEvent func(Event)) error {
+	defer w.fsw.Close()
+	defer w.stopTimers()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err, ok := <-w.fsw.Errors:
+			if !ok {
+				return nil
+			}
+			_ = err // best-effort: a transient watch error shouldn't stop the loop
+		case ev, ok := <-w.fsw.Events:
+			if !ok {
+				return nil
+			}
+			w.handle(ctx, ev, onEvent)
+		}
+	}
+}
+
+func (w *Watcher) handle(ctx context.Context, ev fsnotify.Event, onEvent func(Event)) {
+	if ev.Op&fsnotify.Create != 0 {
+		if info, err := os.Stat(ev.Name); err == nil && info.IsDir() {
+			_ = w.watchDirs(ev.Name)
+			return
+		}
+	}
+
+	if !w.matchesExtension(ev.Name) {
+		return
+	}
+
+	w.debounceEvent(ctx, ev.Name, onEvent)
+}
+
+// matchesExtension reports whether path has one of the indexer's configured
+// source extensions, so we don't debounce-parse every touched dotfile.
+func (w *Watcher) matchesExtension(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, e := range w.idx.config.Extensions {
+		if e == ext {
+			return true
+		}
+	}
+	return false
+}
+
+// debounceEvent resets (or starts) a per-file timer so rapid successive
+// writes to the same file only result in one re-parse, fired w.debounce
+// after the last event for that path.
+func (w *Watcher) debounceEvent(ctx context.Context, path string, onEvent func(Event)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if t, ok := w.timers[path]; ok {
+		t.Stop()
+	}
+
+	w.timers[path] = time.AfterFunc(w.debounce, func() {
+		w.fire(ctx, path, onEvent)
+	})
+}
+
+func (w *Watcher) fire(ctx context.Context, path string, onEvent func(Event)) {
+	w.mu.Lock()
+	delete(w.timers, path)
+	w.mu.Unlock()
+
+	if ctx.Err() != nil {
+		return
+	}
+
+	relPath, err := filepath.Rel(w.root, path)
+	if err != nil {
+		relPath = path
+	}
+
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		onEvent(Event{Path: relPath, Removed: true})
+		return
+	}
+
+	file := &FileInfo{
+		Path:      path,
+		RelPath:   relPath,
+		Extension: strings.ToLower(filepath.Ext(path)),
+	}
+
+	chunks, err := w.idx.ParseFile(file)
+	if err != nil {
+		return // a transient parse error (e.g. mid-save) is retried on the next event
+	}
+
+	onEvent(Event{Path: relPath, Chunks: chunks})
+}
+
+func (w *Watcher) stopTimers() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, t := range w.timers {
+		t.Stop()
+	}
+}
+
+// Close stops the underlying filesystem watch without waiting for Run's ctx
+// to be cancelled.
+func (w *Watcher) Close() error {
+	return w.fsw.Close()
+}
@@ -6,7 +6,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"math/rand"
 	"net/http"
+	"strconv"
 	"time"
 )
 
@@ -14,6 +16,54 @@ import (
 type CodeBERTClient struct {
 	host       string
 	httpClient *http.Client
+	opts       RetryOptions
+}
+
+// RetryClassifier decides whether a request should be retried given the
+// response (may be nil on network errors) and the error returned by the
+// HTTP round trip.
+type RetryClassifier func(resp *http.Response, err error) bool
+
+// Metrics reports per-call retry behavior so callers can track how flaky the
+// embedding service is.
+type Metrics struct {
+	Attempts int
+	WallTime time.Duration
+}
+
+// RetryOptions configures retry, backoff, and timeout behavior for CodeBERTClient.
+type RetryOptions struct {
+	MaxRetries        int
+	InitialBackoff    time.Duration
+	MaxBackoff        time.Duration
+	PerRequestTimeout time.Duration
+	RetryClassifier   RetryClassifier
+	OnMetrics         func(op string, m Metrics)
+}
+
+// DefaultRetryOptions returns sensible defaults: retry network errors,
+// 502/503/504, and 429 (honoring Retry-After), with full-jitter exponential
+// backoff.
+func DefaultRetryOptions() RetryOptions {
+	return RetryOptions{
+		MaxRetries:        3,
+		InitialBackoff:    250 * time.Millisecond,
+		MaxBackoff:        10 * time.Second,
+		PerRequestTimeout: 60 * time.Second,
+		RetryClassifier:   defaultRetryClassifier,
+	}
+}
+
+func defaultRetryClassifier(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
 }
 
 // CodeBERTEmbeddingRequest represents a request to generate embedding
@@ -49,24 +99,135 @@ type CodeBERTHealthResponse struct {
 	Dimension int    `json:"dimension"`
 }
 
-// NewCodeBERTClient creates a new CodeBERT client
+// NewCodeBERTClient creates a new CodeBERT client with default retry/backoff
+// options.
 func NewCodeBERTClient(host string) *CodeBERTClient {
+	return NewCodeBERTClientWithOptions(host, DefaultRetryOptions())
+}
+
+// NewCodeBERTClientWithOptions creates a new CodeBERT client with custom
+// retry/backoff/timeout behavior.
+func NewCodeBERTClientWithOptions(host string, opts RetryOptions) *CodeBERTClient {
+	if opts.MaxRetries <= 0 {
+		opts.MaxRetries = 1
+	}
+	if opts.InitialBackoff <= 0 {
+		opts.InitialBackoff = 250 * time.Millisecond
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = 10 * time.Second
+	}
+	if opts.PerRequestTimeout <= 0 {
+		opts.PerRequestTimeout = 60 * time.Second
+	}
+	if opts.RetryClassifier == nil {
+		opts.RetryClassifier = defaultRetryClassifier
+	}
+
 	return &CodeBERTClient{
-		host: host,
-		httpClient: &http.Client{
-			Timeout: 60 * time.Second,
-		},
+		host:       host,
+		httpClient: &http.Client{},
+		opts:       opts,
 	}
 }
 
-// CheckHealth checks if the CodeBERT service is healthy
-func (c *CodeBERTClient) CheckHealth(ctx context.Context) (*CodeBERTHealthResponse, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", c.host+"/health", nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+// doWithRetry performs reqFn with exponential backoff and full jitter,
+// deriving a per-attempt context.WithTimeout from ctx so the caller can
+// still cancel the whole operation. Each response body is read in full
+// within its attempt's timeout and handed back buffered; the caller closes
+// the body of the response it receives.
+func (c *CodeBERTClient) doWithRetry(ctx context.Context, op string, reqFn func(ctx context.Context) (*http.Response, error)) (*http.Response, error) {
+	start := time.Now()
+	var lastErr error
+
+	for attempt := 0; attempt < c.opts.MaxRetries; attempt++ {
+		attemptCtx, cancel := context.WithTimeout(ctx, c.opts.PerRequestTimeout)
+		resp, err := reqFn(attemptCtx)
+		if err == nil {
+			// Buffer the body while attemptCtx is still live: cancelling a
+			// request's context also aborts reads of its response body.
+			var body []byte
+			body, err = io.ReadAll(resp.Body)
+			resp.Body.Close()
+			resp.Body = io.NopCloser(bytes.NewReader(body))
+		}
+		cancel()
+
+		retry := c.opts.RetryClassifier(resp, err)
+		if !retry {
+			if c.opts.OnMetrics != nil {
+				c.opts.OnMetrics(op, Metrics{Attempts: attempt + 1, WallTime: time.Since(start)})
+			}
+			return resp, err
+		}
+
+		lastErr = err
+		if lastErr == nil {
+			lastErr = fmt.Errorf("retryable status %d", resp.StatusCode)
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+
+		if attempt == c.opts.MaxRetries-1 {
+			break
+		}
+
+		wait := retryAfter(resp)
+		if wait == 0 {
+			wait = fullJitterBackoff(attempt, c.opts.InitialBackoff, c.opts.MaxBackoff)
+		}
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			if c.opts.OnMetrics != nil {
+				c.opts.OnMetrics(op, Metrics{Attempts: attempt + 1, WallTime: time.Since(start)})
+			}
+			return nil, ctx.Err()
+		}
+	}
+
+	if c.opts.OnMetrics != nil {
+		c.opts.OnMetrics(op, Metrics{Attempts: c.opts.MaxRetries, WallTime: time.Since(start)})
+	}
+	return nil, fmt.Errorf("exhausted %d retries: %w", c.opts.MaxRetries, lastErr)
+}
+
+// fullJitterBackoff computes sleep = rand(min(maxBackoff, base * 2^attempt)).
+func fullJitterBackoff(attempt int, base, maxBackoff time.Duration) time.Duration {
+	backoff := base * time.Duration(1<<uint(attempt))
+	if backoff > maxBackoff || backoff <= 0 {
+		backoff = maxBackoff
 	}
+	return time.Duration(rand.Int63n(int64(backoff) + 1))
+}
 
-	resp, err := c.httpClient.Do(req)
+// retryAfter honors a Retry-After header (seconds) on 429 responses.
+func retryAfter(resp *http.Response) time.Duration {
+	if resp == nil || resp.StatusCode != http.StatusTooManyRequests {
+		return 0
+	}
+	v := resp.Header.Get("Retry-After")
+	if v == "" {
+		return 0
+	}
+	secs, err := strconv.Atoi(v)
+	if err != nil || secs <= 0 {
+		return 0
+	}
+	return time.Duration(secs) * time.Second
+}
+
+// CheckHealth checks if the CodeBERT service is healthy
+func (c *CodeBERTClient) CheckHealth(ctx context.Context) (*CodeBERTHealthResponse, error) {
+	resp, err := c.doWithRetry(ctx, "CheckHealth", func(ctx context.Context) (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, "GET", c.host+"/health", nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		return c.httpClient.Do(req)
+	})
 	if err != nil {
 		return nil, fmt.Errorf("CodeBERT service not accessible at %s: %w", c.host, err)
 	}
@@ -97,13 +258,14 @@ func (c *CodeBERTClient) Embed(ctx context.Context, text string) ([]float32, err
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", c.host+"/embed", bytes.NewReader(body))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.doWithRetry(ctx, "Embed", func(ctx context.Context) (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", c.host+"/embed", bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		return c.httpClient.Do(req)
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
@@ -122,12 +284,40 @@ func (c *CodeBERTClient) Embed(ctx context.Context, text string) ([]float32, err
 	return embResp.Embedding, nil
 }
 
-// EmbedBatch generates embeddings for multiple texts
+// EmbedBatch generates embeddings for multiple texts. If a batch fails (for
+// example a 413 for the batch as a whole), it bisects the batch and retries
+// each half, so a batch that fails only because of its combined size still
+// succeeds. A text that fails on its own fails the whole call: its error is
+// returned and no embeddings are returned for the other texts.
 func (c *CodeBERTClient) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
 	if len(texts) == 0 {
 		return [][]float32{}, nil
 	}
 
+	embeddings, err := c.embedBatchOnce(ctx, texts)
+	if err == nil {
+		return embeddings, nil
+	}
+
+	if len(texts) == 1 {
+		return nil, err
+	}
+
+	mid := len(texts) / 2
+	left, leftErr := c.EmbedBatch(ctx, texts[:mid])
+	if leftErr != nil {
+		return nil, leftErr
+	}
+	right, rightErr := c.EmbedBatch(ctx, texts[mid:])
+	if rightErr != nil {
+		return nil, rightErr
+	}
+
+	return append(left, right...), nil
+}
+
+// embedBatchOnce sends a single batch request without bisecting on failure.
+func (c *CodeBERTClient) embedBatchOnce(ctx context.Context, texts []string) ([][]float32, error) {
 	reqBody := CodeBERTBatchRequest{
 		Texts:     texts,
 		MaxLength: 512,
@@ -138,13 +328,14 @@ func (c *CodeBERTClient) EmbedBatch(ctx context.Context, texts []string) ([][]fl
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", c.host+"/embed/batch", bytes.NewReader(body))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.doWithRetry(ctx, "EmbedBatch", func(ctx context.Context) (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", c.host+"/embed/batch", bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		return c.httpClient.Do(req)
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
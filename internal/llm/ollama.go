@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/codementor/codementor/internal/config"
@@ -55,12 +56,22 @@ type EmbeddingResponse struct {
 
 // Client is an Ollama API client
 type Client struct {
+	mu             sync.RWMutex
 	host           string
 	chatModel      string
 	embeddingModel string
 	httpClient     *http.Client
 }
 
+// tagsResponse is the body of a GET /api/tags call, used by
+// SetChatModel/SetEmbeddingModel to validate a model name is actually pulled
+// before switching to it.
+type tagsResponse struct {
+	Models []struct {
+		Name string `json:"name"`
+	} `json:"models"`
+}
+
 // NewClient creates a new Ollama client
 func NewClient(cfg config.OllamaConfig) *Client {
 	return &Client{
@@ -76,7 +87,7 @@ func NewClient(cfg config.OllamaConfig) *Client {
 // Chat sends a chat request and returns the response
 func (c *Client) Chat(ctx context.Context, messages []Message) (string, error) {
 	req := ChatRequest{
-		Model:    c.chatModel,
+		Model:    c.GetChatModel(),
 		Messages: messages,
 		Stream:   false,
 	}
@@ -117,7 +128,7 @@ type StreamHandler func(content string, done bool) error
 // ChatStream sends a chat request and streams the response
 func (c *Client) ChatStream(ctx context.Context, messages []Message, handler StreamHandler) error {
 	req := ChatRequest{
-		Model:    c.chatModel,
+		Model:    c.GetChatModel(),
 		Messages: messages,
 		Stream:   true,
 	}
@@ -166,7 +177,7 @@ func (c *Client) ChatStream(ctx context.Context, messages []Message, handler Str
 // Embed generates embeddings for the given text
 func (c *Client) Embed(ctx context.Context, text string) ([]float32, error) {
 	req := EmbeddingRequest{
-		Model:  c.embeddingModel,
+		Model:  c.GetEmbeddingModel(),
 		Prompt: text,
 	}
 
@@ -237,11 +248,77 @@ func (c *Client) CheckHealth(ctx context.Context) error {
 
 // GetChatModel returns the current chat model
 func (c *Client) GetChatModel() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	return c.chatModel
 }
 
 // GetEmbeddingModel returns the current embedding model
 func (c *Client) GetEmbeddingModel() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	return c.embeddingModel
 }
 
+// SetChatModel switches the chat model Chat/ChatStream use, after confirming
+// via /api/tags that name is actually pulled in Ollama — otherwise Chat
+// would only discover the typo on its next call, as an opaque HTTP error.
+func (c *Client) SetChatModel(ctx context.Context, name string) error {
+	if err := c.checkModelPulled(ctx, name); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.chatModel = name
+	c.mu.Unlock()
+	return nil
+}
+
+// SetEmbeddingModel switches the embedding model Embed/EmbedBatch use, after
+// the same /api/tags existence check as SetChatModel. Callers migrating a
+// vector store to the new model's dimension (see
+// embedding.Embedder.MigrateStore) call this first so every subsequent Embed
+// call already targets name.
+func (c *Client) SetEmbeddingModel(ctx context.Context, name string) error {
+	if err := c.checkModelPulled(ctx, name); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.embeddingModel = name
+	c.mu.Unlock()
+	return nil
+}
+
+// checkModelPulled queries /api/tags and returns an error unless name is
+// among the models Ollama already has pulled.
+func (c *Client) checkModelPulled(ctx context.Context, name string) error {
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", c.host+"/api/tags", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("ollama is not accessible at %s: %w", c.host, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to list models: status %d", resp.StatusCode)
+	}
+
+	var tags tagsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tags); err != nil {
+		return fmt.Errorf("failed to decode models list: %w", err)
+	}
+
+	for _, m := range tags.Models {
+		if m.Name == name {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("model %q is not pulled in ollama", name)
+}
+
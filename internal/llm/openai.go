@@ -0,0 +1,361 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/codementor/codementor/internal/config"
+)
+
+// openAIChatRequest represents a request to /v1/chat/completions
+type openAIChatRequest struct {
+	Model    string    `json:"model"`
+	Messages []Message `json:"messages"`
+	Stream   bool      `json:"stream"`
+}
+
+// openAIChatResponse represents a non-streaming /v1/chat/completions response
+type openAIChatResponse struct {
+	Choices []struct {
+		Message Message `json:"message"`
+	} `json:"choices"`
+}
+
+// openAIChatStreamChunk represents one SSE "data:" event from a streaming
+// /v1/chat/completions response
+type openAIChatStreamChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+		FinishReason *string `json:"finish_reason"`
+	} `json:"choices"`
+}
+
+// openAIEmbeddingRequest represents a request to /v1/embeddings. Unlike
+// Ollama's one-prompt-per-call shape, Input accepts a batch in a single
+// request.
+type openAIEmbeddingRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+// openAIEmbeddingResponse represents a /v1/embeddings response. EmbedBatch
+// places each entry by its position in Data; Index is decoded but not used.
+type openAIEmbeddingResponse struct {
+	Data []struct {
+		Embedding []float32 `json:"embedding"`
+		Index     int       `json:"index"`
+	} `json:"data"`
+}
+
+// openAIModelsResponse is the body of a GET /v1/models call, used by
+// SetChatModel/SetEmbeddingModel to validate a model name before switching.
+type openAIModelsResponse struct {
+	Data []struct {
+		ID string `json:"id"`
+	} `json:"data"`
+}
+
+// OpenAIClient is a Provider for any OpenAI-compatible endpoint (vLLM, LM
+// Studio, llama.cpp server, hosted OpenAI).
+type OpenAIClient struct {
+	mu             sync.RWMutex
+	host           string
+	apiKey         string
+	chatModel      string
+	embeddingModel string
+	httpClient     *http.Client
+}
+
+// NewOpenAIClient creates a new OpenAI-compatible client
+func NewOpenAIClient(cfg config.OllamaConfig) *OpenAIClient {
+	return &OpenAIClient{
+		host:           strings.TrimRight(cfg.Host, "/"),
+		apiKey:         cfg.APIKey,
+		chatModel:      cfg.ChatModel,
+		embeddingModel: cfg.EmbeddingModel,
+		httpClient: &http.Client{
+			Timeout: time.Duration(cfg.Timeout) * time.Second,
+		},
+	}
+}
+
+// authorize sets the Authorization header when an API key is configured;
+// hosted OpenAI requires it, but local servers like llama.cpp often don't.
+func (c *OpenAIClient) authorize(req *http.Request) {
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+}
+
+// Chat sends a chat request and returns the response
+func (c *OpenAIClient) Chat(ctx context.Context, messages []Message) (string, error) {
+	req := openAIChatRequest{
+		Model:    c.GetChatModel(),
+		Messages: messages,
+		Stream:   false,
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.host+"/v1/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	c.authorize(httpReq)
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return "", fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("openai-compatible endpoint returned status %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	var chatResp openAIChatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&chatResp); err != nil {
+		return "", fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if len(chatResp.Choices) == 0 {
+		return "", fmt.Errorf("openai-compatible endpoint returned no choices")
+	}
+
+	return chatResp.Choices[0].Message.Content, nil
+}
+
+// ChatStream sends a chat request and streams the response, parsing the
+// SSE "data: {...}" framing /v1/chat/completions uses, terminated by a
+// literal "data: [DONE]" event.
+func (c *OpenAIClient) ChatStream(ctx context.Context, messages []Message, handler StreamHandler) error {
+	req := openAIChatRequest{
+		Model:    c.GetChatModel(),
+		Messages: messages,
+		Stream:   true,
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.host+"/v1/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "text/event-stream")
+	c.authorize(httpReq)
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("openai-compatible endpoint returned status %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		payload := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if payload == "[DONE]" {
+			return handler("", true)
+		}
+
+		var chunk openAIChatStreamChunk
+		if err := json.Unmarshal([]byte(payload), &chunk); err != nil {
+			continue
+		}
+		if len(chunk.Choices) == 0 {
+			continue
+		}
+
+		done := chunk.Choices[0].FinishReason != nil
+		if err := handler(chunk.Choices[0].Delta.Content, done); err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+	}
+
+	return scanner.Err()
+}
+
+// Embed generates an embedding for the given text
+func (c *OpenAIClient) Embed(ctx context.Context, text string) ([]float32, error) {
+	embeddings, err := c.EmbedBatch(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return embeddings[0], nil
+}
+
+// EmbedBatch generates embeddings for multiple texts in a single request
+func (c *OpenAIClient) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	req := openAIEmbeddingRequest{
+		Model: c.GetEmbeddingModel(),
+		Input: texts,
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.host+"/v1/embeddings", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	c.authorize(httpReq)
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("openai-compatible endpoint returned status %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	var embResp openAIEmbeddingResponse
+	if err := json.NewDecoder(resp.Body).Decode(&embResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if len(embResp.Data) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(embResp.Data))
+	}
+
+	// The spec guarantees data comes back in input order; Index is only
+	// meant as a convenience, and some OpenAI-compatible servers omit it
+	// entirely (leaving every entry at the Go zero-value 0), so positional
+	// order is used instead of trusting Index.
+	embeddings := make([][]float32, len(texts))
+	for i, d := range embResp.Data {
+		embeddings[i] = d.Embedding
+	}
+
+	return embeddings, nil
+}
+
+// CheckHealth checks if the endpoint is running and accessible
+func (c *OpenAIClient) CheckHealth(ctx context.Context) error {
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", c.host+"/v1/models", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	c.authorize(httpReq)
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("openai-compatible endpoint is not accessible at %s: %w", c.host, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("openai-compatible endpoint health check failed with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// GetChatModel returns the current chat model
+func (c *OpenAIClient) GetChatModel() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.chatModel
+}
+
+// GetEmbeddingModel returns the current embedding model
+func (c *OpenAIClient) GetEmbeddingModel() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.embeddingModel
+}
+
+// SetChatModel switches the chat model Chat/ChatStream use, after confirming
+// via /v1/models that name is actually served — otherwise Chat would only
+// discover the typo on its next call, as an opaque HTTP error.
+func (c *OpenAIClient) SetChatModel(ctx context.Context, name string) error {
+	if err := c.checkModelServed(ctx, name); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.chatModel = name
+	c.mu.Unlock()
+	return nil
+}
+
+// SetEmbeddingModel switches the embedding model Embed/EmbedBatch use, after
+// the same /v1/models existence check as SetChatModel.
+func (c *OpenAIClient) SetEmbeddingModel(ctx context.Context, name string) error {
+	if err := c.checkModelServed(ctx, name); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.embeddingModel = name
+	c.mu.Unlock()
+	return nil
+}
+
+// checkModelServed queries /v1/models and returns an error unless name is
+// among the models the endpoint currently serves.
+func (c *OpenAIClient) checkModelServed(ctx context.Context, name string) error {
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", c.host+"/v1/models", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	c.authorize(httpReq)
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("openai-compatible endpoint is not accessible at %s: %w", c.host, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to list models: status %d", resp.StatusCode)
+	}
+
+	var models openAIModelsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&models); err != nil {
+		return fmt.Errorf("failed to decode models list: %w", err)
+	}
+
+	for _, m := range models.Data {
+		if m.ID == name {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("model %q is not served by %s", name, c.host)
+}
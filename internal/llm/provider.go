@@ -0,0 +1,49 @@
+package llm
+
+import (
+	"context"
+
+	"github.com/codementor/codementor/internal/config"
+)
+
+// Provider is the interface every chat+embedding backend implements. It lets
+// Embedder, HybridRetriever and RAGAgent talk to Ollama, an OpenAI-compatible
+// endpoint (vLLM, LM Studio, llama.cpp server, hosted OpenAI), or any future
+// backend interchangeably, without branching on provider type anywhere
+// outside NewProvider.
+type Provider interface {
+	// Chat sends a chat request and returns the response
+	Chat(ctx context.Context, messages []Message) (string, error)
+
+	// ChatStream sends a chat request and streams the response
+	ChatStream(ctx context.Context, messages []Message, handler StreamHandler) error
+
+	// Embed generates an embedding for a single text
+	Embed(ctx context.Context, text string) ([]float32, error)
+
+	// EmbedBatch generates embeddings for multiple texts. The OpenAI-compatible
+	// client sends them in one request; the Ollama client sends one request
+	// per text.
+	EmbedBatch(ctx context.Context, texts []string) ([][]float32, error)
+
+	// CheckHealth checks if the backend is running and accessible
+	CheckHealth(ctx context.Context) error
+
+	GetChatModel() string
+	GetEmbeddingModel() string
+	SetChatModel(ctx context.Context, name string) error
+	SetEmbeddingModel(ctx context.Context, name string) error
+}
+
+// NewProvider constructs the Provider backend selected by cfg.Provider:
+// "openai" talks to any OpenAI-compatible endpoint at cfg.Host, anything else
+// (including the empty string, for existing configs) defaults to Ollama's
+// native API.
+func NewProvider(cfg config.OllamaConfig) Provider {
+	switch cfg.Provider {
+	case "openai":
+		return NewOpenAIClient(cfg)
+	default:
+		return NewClient(cfg)
+	}
+}
@@ -0,0 +1,361 @@
+// Package pipeline streams repository indexing through three bounded
+// stages — parse, batch, embed — instead of a fixed `sem := make(chan
+// struct{}, 10)` limit like the one the indexer's own parse loop uses. Bounded
+// channels between stages apply backpressure automatically: if embedding
+// falls behind, the batcher blocks, which blocks parse workers, so memory
+// never balloons ahead of a slow embedding service.
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/codementor/codementor/internal/embedcache"
+	"github.com/codementor/codementor/internal/embedding"
+	"github.com/codementor/codementor/internal/indexer"
+	"github.com/codementor/codementor/internal/llm"
+	"github.com/codementor/codementor/internal/retriever"
+)
+
+// Stats holds per-stage counters so callers can see which stage is the
+// bottleneck while a pipeline run is in flight.
+type Stats struct {
+	Parsed   int64
+	Queued   int64
+	Embedded int64
+	Stored   int64
+}
+
+// Config controls pipeline concurrency and batching.
+type Config struct {
+	ParseWorkers    int
+	EmbedWorkers    int
+	BatchSize       int
+	MinBatchSize    int
+	MaxBatchLatency time.Duration
+	QueueSize       int // bounded channel size between stages
+}
+
+// DefaultConfig returns reasonable defaults for indexing a typical repo.
+func DefaultConfig() Config {
+	return Config{
+		ParseWorkers:    10,
+		EmbedWorkers:    4,
+		BatchSize:       20,
+		MinBatchSize:    1,
+		MaxBatchLatency: 2 * time.Second,
+		QueueSize:       100,
+	}
+}
+
+// Pipeline parses files, batches their chunks, and embeds the batches
+// concurrently, writing embedded chunks to a VectorStore as they complete.
+type Pipeline struct {
+	cfg     Config
+	client  *llm.CodeBERTClient
+	store   retriever.VectorStore
+	stats   Stats
+	onStats func(Stats)
+
+	batchSize int64 // current adaptive batch size, atomic
+
+	cache      *embedcache.Cache
+	cacheModel string
+}
+
+// SetCache installs a content-addressed cache in front of EmbedBatch, keyed
+// by each chunk's ContentHash and model. Cache hits skip the network call
+// entirely; misses are embedded and back-filled into the cache.
+func (p *Pipeline) SetCache(cache *embedcache.Cache, model string) {
+	p.cache = cache
+	p.cacheModel = model
+}
+
+// New creates a Pipeline that embeds via client and stores results in store.
+// onStats, if non-nil, is called after every counter update so a caller can
+// forward per-stage progress to a UI.
+func New(cfg Config, client *llm.CodeBERTClient, store retriever.VectorStore, onStats func(Stats)) *Pipeline {
+	if cfg.ParseWorkers <= 0 {
+		cfg.ParseWorkers = 10
+	}
+	if cfg.EmbedWorkers <= 0 {
+		cfg.EmbedWorkers = 4
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 20
+	}
+	if cfg.MinBatchSize <= 0 {
+		cfg.MinBatchSize = 1
+	}
+	if cfg.MaxBatchLatency <= 0 {
+		cfg.MaxBatchLatency = 2 * time.Second
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 100
+	}
+
+	return &Pipeline{
+		cfg:       cfg,
+		client:    client,
+		store:     store,
+		onStats:   onStats,
+		batchSize: int64(cfg.BatchSize),
+	}
+}
+
+// Stats returns a snapshot of the current per-stage counters.
+func (p *Pipeline) Stats() Stats {
+	return Stats{
+		Parsed:   atomic.LoadInt64(&p.stats.Parsed),
+		Queued:   atomic.LoadInt64(&p.stats.Queued),
+		Embedded: atomic.LoadInt64(&p.stats.Embedded),
+		Stored:   atomic.LoadInt64(&p.stats.Stored),
+	}
+}
+
+func (p *Pipeline) bump(counter *int64, delta int64) {
+	atomic.AddInt64(counter, delta)
+	if p.onStats != nil {
+		p.onStats(p.Stats())
+	}
+}
+
+// Run parses files, batches their chunks, and embeds+stores them, returning
+// once every file has been processed or ctx is cancelled. parseFn is the
+// per-file parser (the indexer's ParseFile, which dispatches by extension
+// through its Parser registry).
+func (p *Pipeline) Run(ctx context.Context, files []*indexer.FileInfo, parseFn func(*indexer.FileInfo) ([]*indexer.CodeChunk, error)) error {
+	chunkCh := make(chan *indexer.CodeChunk, p.cfg.QueueSize)
+	batchCh := make(chan []*indexer.CodeChunk, p.cfg.QueueSize)
+
+	var parseWG sync.WaitGroup
+	var embedWG sync.WaitGroup
+	errCh := make(chan error, p.cfg.ParseWorkers+p.cfg.EmbedWorkers+1)
+
+	// Stage 1: parse workers.
+	fileCh := make(chan *indexer.FileInfo)
+	for i := 0; i < p.cfg.ParseWorkers; i++ {
+		parseWG.Add(1)
+		go func() {
+			defer parseWG.Done()
+			for file := range fileCh {
+				chunks, err := parseFn(file)
+				if err != nil {
+					continue // a single unparseable file shouldn't abort the run
+				}
+				for _, c := range chunks {
+					select {
+					case chunkCh <- c:
+						p.bump(&p.stats.Parsed, 1)
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(fileCh)
+		for _, f := range files {
+			select {
+			case fileCh <- f:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	go func() {
+		parseWG.Wait()
+		close(chunkCh)
+	}()
+
+	// Stage 2: batcher — flush on size or a max-latency timer.
+	go p.batch(ctx, chunkCh, batchCh)
+
+	// Stage 3: embed workers.
+	for i := 0; i < p.cfg.EmbedWorkers; i++ {
+		embedWG.Add(1)
+		go func() {
+			defer embedWG.Done()
+			for batch := range batchCh {
+				if err := p.embedAndStore(ctx, batch); err != nil {
+					select {
+					case errCh <- err:
+					default:
+					}
+				}
+			}
+		}()
+	}
+
+	embedWG.Wait()
+
+	select {
+	case err := <-errCh:
+		return err
+	default:
+		return nil
+	}
+}
+
+// batch groups chunks into batches of the current adaptive size, flushing
+// early if MaxBatchLatency elapses since the first chunk in the batch.
+func (p *Pipeline) batch(ctx context.Context, in <-chan *indexer.CodeChunk, out chan<- []*indexer.CodeChunk) {
+	defer close(out)
+
+	var pending []*indexer.CodeChunk
+	timer := time.NewTimer(p.cfg.MaxBatchLatency)
+	defer timer.Stop()
+
+	flush := func() {
+		if len(pending) == 0 {
+			return
+		}
+		select {
+		case out <- pending:
+			p.bump(&p.stats.Queued, int64(len(pending)))
+		case <-ctx.Done():
+		}
+		pending = nil
+	}
+
+	for {
+		size := int(atomic.LoadInt64(&p.batchSize))
+
+		select {
+		case c, ok := <-in:
+			if !ok {
+				flush()
+				return
+			}
+			if len(pending) == 0 {
+				timer.Reset(p.cfg.MaxBatchLatency)
+			}
+			pending = append(pending, c)
+			if len(pending) >= size {
+				flush()
+			}
+		case <-timer.C:
+			flush()
+			timer.Reset(p.cfg.MaxBatchLatency)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// embedAndStore embeds one batch and inserts the results into the vector
+// store, shrinking the adaptive batch size on a 413/OOM-style failure and
+// splitting the batch in half to make forward progress, or growing it back
+// on sustained success.
+func (p *Pipeline) embedAndStore(ctx context.Context, batch []*indexer.CodeChunk) error {
+	if len(batch) == 0 {
+		return nil
+	}
+
+	vectors := make([][]float32, len(batch))
+	var missIdx []int
+	var missTexts []string
+
+	if p.cache != nil {
+		for i, c := range batch {
+			key := embedcache.Key(c.ContentHash, p.cacheModel)
+			if v, ok := p.cache.Get(key); ok {
+				vectors[i] = v
+				continue
+			}
+			missIdx = append(missIdx, i)
+			missTexts = append(missTexts, c.Content)
+		}
+	} else {
+		for i, c := range batch {
+			missIdx = append(missIdx, i)
+			missTexts = append(missTexts, c.Content)
+		}
+	}
+
+	if len(missTexts) > 0 {
+		missVectors, err := p.client.EmbedBatch(ctx, missTexts)
+		if err != nil {
+			if isOversizedBatch(err) && len(batch) > p.cfg.MinBatchSize {
+				p.shrinkBatchSize()
+				mid := len(batch) / 2
+				if err := p.embedAndStore(ctx, batch[:mid]); err != nil {
+					return err
+				}
+				return p.embedAndStore(ctx, batch[mid:])
+			}
+			return err
+		}
+		p.growBatchSize()
+
+		for j, i := range missIdx {
+			vectors[i] = missVectors[j]
+			if p.cache != nil {
+				key := embedcache.Key(batch[i].ContentHash, p.cacheModel)
+				if err := p.cache.Put(key, missVectors[j]); err != nil {
+					return fmt.Errorf("failed to write embed cache: %w", err)
+				}
+			}
+		}
+	}
+
+	embedded := make([]*embedding.EmbeddedChunk, len(batch))
+	for i, c := range batch {
+		embedded[i] = &embedding.EmbeddedChunk{Chunk: c, Embedding: vectors[i]}
+	}
+	p.bump(&p.stats.Embedded, int64(len(embedded)))
+
+	if err := p.store.Insert(embedded); err != nil {
+		return err
+	}
+	p.bump(&p.stats.Stored, int64(len(embedded)))
+
+	return nil
+}
+
+func (p *Pipeline) shrinkBatchSize() {
+	for {
+		cur := atomic.LoadInt64(&p.batchSize)
+		next := cur / 2
+		if next < int64(p.cfg.MinBatchSize) {
+			next = int64(p.cfg.MinBatchSize)
+		}
+		if next == cur {
+			return
+		}
+		if atomic.CompareAndSwapInt64(&p.batchSize, cur, next) {
+			return
+		}
+	}
+}
+
+func (p *Pipeline) growBatchSize() {
+	for {
+		cur := atomic.LoadInt64(&p.batchSize)
+		maxSize := int64(p.cfg.BatchSize)
+		if cur >= maxSize {
+			return
+		}
+		next := cur + 1
+		if atomic.CompareAndSwapInt64(&p.batchSize, cur, next) {
+			return
+		}
+	}
+}
+
+// isOversizedBatch reports whether err looks like a 413 Payload Too Large
+// or out-of-memory response from the embedding service.
+func isOversizedBatch(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "413") || strings.Contains(msg, "too large") || strings.Contains(msg, "out of memory") || strings.Contains(msg, "oom")
+}
@@ -0,0 +1,72 @@
+// Package progress provides a small progress-reporting abstraction used by
+// long-running operations like repository indexing. It decouples the work
+// being tracked from how that progress is displayed, so the same indexing
+// code can drive a terminal bar, stay silent in library use, or feed a test.
+package progress
+
+import (
+	"time"
+)
+
+// Reporter tracks progress through a bounded amount of work and renders it
+// however the implementation sees fit.
+type Reporter interface {
+	// Start begins tracking a new stage with the given total unit count.
+	// A total of 0 means the size isn't known in advance.
+	Start(stage string, total int)
+
+	// Update advances the current stage by delta units.
+	Update(delta int)
+
+	// SetSpeed overrides the auto-computed rate (units/sec) for the current
+	// stage. Most callers can rely on the EWMA computed internally instead.
+	SetSpeed(unitsPerSec float64)
+
+	// ETA returns the estimated time remaining for the current stage based
+	// on the observed rate, or 0 if it can't be estimated yet.
+	ETA() time.Duration
+
+	// Finish marks the current stage complete.
+	Finish()
+}
+
+// NullReporter is a no-op Reporter for library consumers and tests that
+// don't want any progress output.
+type NullReporter struct{}
+
+// NewNullReporter creates a Reporter that discards all progress.
+func NewNullReporter() *NullReporter { return &NullReporter{} }
+
+func (n *NullReporter) Start(stage string, total int) {}
+func (n *NullReporter) Update(delta int)               {}
+func (n *NullReporter) SetSpeed(unitsPerSec float64)   {}
+func (n *NullReporter) ETA() time.Duration             { return 0 }
+func (n *NullReporter) Finish()                        {}
+
+// AsProgressFn adapts a Reporter into the `func(stage string, current, total
+// int)` callback shape used by RAGAgent.IndexRepository, so existing
+// callers can plug a Reporter in without changing that signature.
+func AsProgressFn(r Reporter) func(stage string, current, total int) {
+	var lastStage string
+	var lastCurrent int
+
+	return func(stage string, current, total int) {
+		if stage != lastStage {
+			if lastStage != "" {
+				r.Finish()
+			}
+			r.Start(stage, total)
+			lastStage = stage
+			lastCurrent = 0
+		}
+
+		if delta := current - lastCurrent; delta > 0 {
+			r.Update(delta)
+			lastCurrent = current
+		}
+
+		if total > 0 && current >= total {
+			r.Finish()
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package progress
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// NewReporter returns a TerminalReporter, or a NullReporter when silent is
+// true (wired up by a CLI's --silent/--no-progress flag).
+func NewReporter(silent bool) Reporter {
+	if silent {
+		return NewNullReporter()
+	}
+	return NewTerminalReporter()
+}
+
+// WatchInterrupt installs a SIGINT/SIGTERM handler that cancels cancel and
+// prints "Aborted" so a long-running index can be stopped cleanly instead of
+// leaving the vector store in a partial state; in-flight embedding batches
+// observe ctx cancellation and the checkpoint flush on their own. Callers
+// should invoke the returned stop function once the operation finishes
+// normally to remove the handler.
+func WatchInterrupt(ctx context.Context, cancel context.CancelFunc) (stop func()) {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-sigCh:
+			fmt.Fprintln(os.Stderr, "\nAborted")
+			cancel()
+		case <-ctx.Done():
+		case <-done:
+		}
+	}()
+
+	return func() {
+		close(done)
+		signal.Stop(sigCh)
+	}
+}
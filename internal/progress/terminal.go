@@ -0,0 +1,135 @@
+package progress
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ewmaAlpha controls how quickly the speed estimate reacts to new samples;
+// lower values smooth more, higher values track bursts more closely.
+const ewmaAlpha = 0.3
+
+// barWidth is the number of characters used to render the progress bar.
+const barWidth = 30
+
+// TerminalReporter renders a progress bar, rate, and ETA to stderr. It is
+// safe for concurrent use since Update is typically called from multiple
+// embedding workers.
+type TerminalReporter struct {
+	mu sync.Mutex
+
+	stage   string
+	total   int
+	current int
+
+	speed     float64 // EWMA of units/sec
+	lastTick  time.Time
+	startedAt time.Time
+}
+
+// NewTerminalReporter creates a Reporter that renders a bar, rate, and ETA.
+func NewTerminalReporter() *TerminalReporter {
+	return &TerminalReporter{}
+}
+
+func (t *TerminalReporter) Start(stage string, total int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.stage = stage
+	t.total = total
+	t.current = 0
+	t.speed = 0
+	t.startedAt = time.Now()
+	t.lastTick = t.startedAt
+
+	t.renderLocked()
+}
+
+func (t *TerminalReporter) Update(delta int) {
+	if delta <= 0 {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	elapsed := now.Sub(t.lastTick).Seconds()
+	t.current += delta
+
+	if elapsed > 0 {
+		instant := float64(delta) / elapsed
+		if t.speed == 0 {
+			t.speed = instant
+		} else {
+			t.speed = ewmaAlpha*instant + (1-ewmaAlpha)*t.speed
+		}
+	}
+	t.lastTick = now
+
+	t.renderLocked()
+}
+
+func (t *TerminalReporter) SetSpeed(unitsPerSec float64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.speed = unitsPerSec
+}
+
+func (t *TerminalReporter) ETA() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.etaLocked()
+}
+
+func (t *TerminalReporter) etaLocked() time.Duration {
+	if t.speed <= 0 || t.total <= 0 {
+		return 0
+	}
+	remaining := t.total - t.current
+	if remaining <= 0 {
+		return 0
+	}
+	return time.Duration(float64(remaining)/t.speed) * time.Second
+}
+
+func (t *TerminalReporter) Finish() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.total > 0 {
+		t.current = t.total
+	}
+	t.renderLocked()
+	fmt.Fprintln(os.Stderr)
+}
+
+// renderLocked draws the current state of the bar. Callers must hold t.mu.
+func (t *TerminalReporter) renderLocked() {
+	var pct float64
+	if t.total > 0 {
+		pct = float64(t.current) / float64(t.total)
+		if pct > 1 {
+			pct = 1
+		}
+	}
+
+	filled := int(pct * barWidth)
+	bar := strings.Repeat("=", filled) + strings.Repeat(" ", barWidth-filled)
+
+	eta := t.etaLocked()
+	fmt.Fprintf(os.Stderr, "\r%s [%s] %d/%d (%.1f/s, ETA %s)   ",
+		t.stage, bar, t.current, t.total, t.speed, formatETA(eta))
+}
+
+func formatETA(d time.Duration) string {
+	if d <= 0 {
+		return "--"
+	}
+	d = d.Round(time.Second)
+	return d.String()
+}
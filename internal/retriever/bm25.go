@@ -1,146 +1,485 @@
 package retriever
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
 	"math"
+	"os"
+	"path/filepath"
 	"regexp"
 	"sort"
 	"strings"
+	"sync"
 
 	"github.com/codementor/codementor/internal/indexer"
 )
 
-// BM25 implements the BM25 ranking algorithm for keyword search
+// bm25ManifestVersion is bumped whenever the on-disk manifest/segment format
+// changes; openExisting rejects a manifest written by a different version
+// instead of trying to interpret fields that may mean something else now.
+const bm25ManifestVersion = 1
+
+// bm25Manifest is the on-disk header for a segmented BM25 index: which
+// segments currently make it up, and the corpus-wide stats (totalDocs,
+// avgDocLen) that every segment's IDF/length-normalization math depends on.
+// It's written atomically, the same temp-file-plus-rename pattern the
+// checkpoint package uses for its index state. Version and Checksum guard
+// against loading a manifest that's stale, corrupt, or from an incompatible
+// build: openExisting starts fresh rather than scoring against segments that
+// no longer match the stats recorded for them.
+type bm25Manifest struct {
+	Segments  []string `json:"segments"`
+	TotalDocs int      `json:"total_docs"`
+	AvgDocLen float64  `json:"avg_doc_len"`
+	NextSeg   int      `json:"next_seg"`
+	Version   int      `json:"version"`
+	Checksum  string   `json:"checksum"`
+}
+
+// manifestChecksum hashes the fields of a bm25Manifest that matter for
+// correctness (everything but Version/Checksum itself), so a truncated or
+// hand-edited manifest.json is caught instead of silently producing
+// mismatched scores.
+func manifestChecksum(segments []string, totalDocs int, avgDocLen float64, nextSeg int) string {
+	h := sha256.New()
+	for _, id := range segments {
+		h.Write([]byte(id))
+		h.Write([]byte{0})
+	}
+	fmt.Fprintf(h, "%d|%f|%d", totalDocs, avgDocLen, nextSeg)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// chunkLocation records which segment (and local doc ID within it) holds a
+// given chunk ID, so Update can tombstone the right doc without scanning
+// every segment.
+type chunkLocation struct {
+	segment *segment
+	docID   uint32
+}
+
+// BM25 implements BM25 ranking over a segmented, on-disk index instead of
+// rebuilding an in-memory index from the full chunk set on every run. Index
+// and Update each write one new immutable segment file (postings as
+// delta-varint doc-ID lists, doc content and a sorted term dictionary,
+// mmap'd back in after writing); Search scores candidates across every open
+// segment using a manifest-tracked global totalDocs/avgDocLen. Deletes are
+// O(1) tombstone-bitmap writes, and Merge later folds small segments
+// together and physically drops tombstoned docs. This is the same shape as
+// Bleve's scorch index, scaled down to what a single-process tool needs —
+// notably using a sorted term table instead of an FST, and hand-rolled
+// delta-varint posting lists instead of roaring bitmaps, since neither
+// library is available without introducing dependency management this tree
+// doesn't have.
 type BM25 struct {
-	k1          float64
-	b           float64
-	chunks      []*indexer.CodeChunk
-	docLengths  []int
-	avgDocLen   float64
-	termFreqs   []map[string]int
-	docFreqs    map[string]int
-	totalDocs   int
-	tokenRegex  *regexp.Regexp
-}
-
-// NewBM25 creates a new BM25 index
+	k1 float64
+	b  float64
+
+	dir        string
+	tokenRegex *regexp.Regexp
+
+	loadOnce sync.Once
+
+	mu        sync.Mutex
+	segments  []*segment
+	chunkLoc  map[string]chunkLocation
+	totalDocs int
+	avgDocLen float64
+	nextSeg   int
+}
+
+// NewBM25 creates a BM25 index backed by segment files under
+// .codementor/bm25, the same relative-path convention the checkpoint and
+// embedding cache packages use. Any segments already on disk from a
+// previous run are opened lazily, on first Search/Index/Update/Merge call.
 func NewBM25() *BM25 {
+	return NewBM25In(filepath.Join(".codementor", "bm25"))
+}
+
+// NewBM25In creates a BM25 index backed by segment files under dir. Segments
+// already on disk there aren't opened until the index is actually used, so
+// constructing one costs nothing for a session that only indexes (or never
+// searches at all).
+func NewBM25In(dir string) *BM25 {
 	return &BM25{
 		k1:         1.5,
 		b:          0.75,
-		docFreqs:   make(map[string]int),
+		dir:        dir,
 		tokenRegex: regexp.MustCompile(`[a-zA-Z_][a-zA-Z0-9_]*`),
+		chunkLoc:   make(map[string]chunkLocation),
+	}
+}
+
+// ensureLoaded opens any manifest/segments already on disk the first time
+// the index is actually used. It's called before every public method
+// acquires b.mu, so concurrent first-callers block on the same sync.Once
+// instead of racing to open the manifest twice.
+func (b *BM25) ensureLoaded() {
+	b.loadOnce.Do(func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.openExisting()
+	})
+}
+
+// openExisting loads the manifest and mmaps every segment it lists, best
+// effort: a missing manifest just means a fresh index, and a segment that
+// fails to open is skipped rather than failing the whole index (a crash
+// mid-write can leave a dangling manifest entry; build/writeNewSegment's
+// temp-file-plus-rename means the segment file itself is never partially
+// written, but a hard kill between writing the segment and saving the
+// manifest could still leave one orphaned either way). A version mismatch
+// or failed checksum is treated the same as a missing manifest — starting
+// fresh and rebuilding on the next Index call is always safe, where scoring
+// against a manifest that doesn't actually match the segments on disk would
+// silently return garbage results.
+func (b *BM25) openExisting() {
+	data, err := os.ReadFile(filepath.Join(b.dir, "manifest.json"))
+	if err != nil {
+		return
+	}
+
+	var m bm25Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return
+	}
+
+	if m.Version != bm25ManifestVersion {
+		fmt.Printf("⚠️  BM25 manifest at %s is version %d, expected %d; starting fresh\n", b.dir, m.Version, bm25ManifestVersion)
+		return
 	}
+
+	if manifestChecksum(m.Segments, m.TotalDocs, m.AvgDocLen, m.NextSeg) != m.Checksum {
+		fmt.Printf("⚠️  BM25 manifest at %s failed its checksum; starting fresh\n", b.dir)
+		return
+	}
+
+	for _, id := range m.Segments {
+		seg, err := openSegment(filepath.Join(b.dir, id+".seg"), id)
+		if err != nil {
+			continue
+		}
+		b.segments = append(b.segments, seg)
+		b.indexChunkLocations(seg)
+	}
+
+	b.totalDocs = m.TotalDocs
+	b.avgDocLen = m.AvgDocLen
+	b.nextSeg = m.NextSeg
 }
 
-// Index builds the BM25 index from chunks
+// indexChunkLocations decodes every live doc in seg to populate chunkLoc.
+// There's no separate on-disk chunk-ID index, so this is the cost of being
+// able to tombstone by chunk ID later; it's still just a JSON decode per
+// doc, far cheaper than re-tokenizing and rebuilding postings.
+func (b *BM25) indexChunkLocations(seg *segment) {
+	for docID := 0; docID < seg.docCount; docID++ {
+		if !seg.isLive(uint32(docID)) {
+			continue
+		}
+		chunk, err := seg.decodeDoc(uint32(docID))
+		if err != nil {
+			continue
+		}
+		b.chunkLoc[chunk.ID] = chunkLocation{segment: seg, docID: uint32(docID)}
+	}
+}
+
+// Index replaces the entire index with a single fresh segment built from
+// chunks. Existing callers that re-parse the whole repo and call Index once
+// at startup get one big segment, same as the old in-memory rebuild; Update
+// is what adds small segments incrementally afterward.
 func (b *BM25) Index(chunks []*indexer.CodeChunk) {
-	b.chunks = chunks
-	b.totalDocs = len(chunks)
-	b.termFreqs = make([]map[string]int, len(chunks))
-	b.docLengths = make([]int, len(chunks))
-	b.docFreqs = make(map[string]int)
-
-	totalLen := 0
-
-	for i, chunk := range chunks {
-		tokens := b.tokenize(chunk.Content)
-		b.docLengths[i] = len(tokens)
-		totalLen += len(tokens)
-
-		// Count term frequencies
-		tf := make(map[string]int)
-		seenTerms := make(map[string]bool)
-
-		for _, token := range tokens {
-			tf[token]++
-			if !seenTerms[token] {
-				b.docFreqs[token]++
-				seenTerms[token] = true
-			}
+	b.ensureLoaded()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, s := range b.segments {
+		_ = s.delete()
+	}
+	b.segments = nil
+	b.chunkLoc = make(map[string]chunkLocation)
+	b.nextSeg = 0
+	b.totalDocs = 0
+	b.avgDocLen = 0
+
+	if len(chunks) == 0 {
+		_ = b.saveManifest()
+		return
+	}
+
+	seg, err := b.writeNewSegment(chunks)
+	if err != nil {
+		fmt.Printf("⚠️  Failed to write BM25 segment: %v\n", err)
+		return
+	}
+
+	b.segments = append(b.segments, seg)
+	b.indexChunkLocations(seg)
+	b.recomputeGlobalStats()
+	if err := b.saveManifest(); err != nil {
+		fmt.Printf("⚠️  Failed to persist BM25 manifest: %v\n", err)
+	}
+}
+
+// Update incrementally applies added and removed chunks: removed chunks are
+// tombstoned (O(1), no segment rewrite), and added chunks are written as
+// one new small segment.
+func (b *BM25) Update(added []*indexer.CodeChunk, removedIDs []string) {
+	b.ensureLoaded()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, id := range removedIDs {
+		loc, ok := b.chunkLoc[id]
+		if !ok {
+			continue
 		}
-		b.termFreqs[i] = tf
+		if err := loc.segment.tombstone(loc.docID); err != nil {
+			fmt.Printf("⚠️  Failed to tombstone chunk %s: %v\n", id, err)
+			continue
+		}
+		delete(b.chunkLoc, id)
 	}
 
-	if len(chunks) > 0 {
-		b.avgDocLen = float64(totalLen) / float64(len(chunks))
+	if len(added) > 0 {
+		seg, err := b.writeNewSegment(added)
+		if err != nil {
+			fmt.Printf("⚠️  Failed to write BM25 segment: %v\n", err)
+		} else {
+			b.segments = append(b.segments, seg)
+			b.indexChunkLocations(seg)
+		}
+	}
+
+	b.recomputeGlobalStats()
+	if err := b.saveManifest(); err != nil {
+		fmt.Printf("⚠️  Failed to persist BM25 manifest: %v\n", err)
 	}
 }
 
-// Search performs BM25 search
-func (b *BM25) Search(query string, topK int) []*SearchResult {
-	if b.totalDocs == 0 {
-		return nil
+// Merge folds small segments together using a simple tiered policy: any
+// segment under mergeThreshold live docs is a merge candidate, and once
+// there are at least two, they're combined into a single new segment with
+// tombstoned docs physically dropped. This is the only point at which a
+// tombstoned doc's storage is reclaimed. Nothing in this repository calls
+// Merge or schedules it in the background; segments are merged only when a
+// caller invokes it.
+func (b *BM25) Merge() error {
+	b.ensureLoaded()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	const mergeThreshold = 1000 // live docs; segments below this get merged
+
+	var small, big []*segment
+	for _, s := range b.segments {
+		if s.liveDocCount() < mergeThreshold {
+			small = append(small, s)
+		} else {
+			big = append(big, s)
+		}
 	}
 
-	queryTokens := b.tokenize(query)
-	if len(queryTokens) == 0 {
+	if len(small) < 2 {
 		return nil
 	}
 
-	// Calculate BM25 scores
-	type scored struct {
-		idx   int
-		score float64
+	var merged []*indexer.CodeChunk
+	for _, s := range small {
+		for docID := 0; docID < s.docCount; docID++ {
+			if !s.isLive(uint32(docID)) {
+				continue
+			}
+			chunk, err := s.decodeDoc(uint32(docID))
+			if err != nil {
+				return fmt.Errorf("failed to decode doc during merge: %w", err)
+			}
+			merged = append(merged, chunk)
+		}
+	}
+
+	newSeg, err := b.writeNewSegment(merged)
+	if err != nil {
+		return fmt.Errorf("failed to write merged segment: %w", err)
 	}
 
-	var scores []scored
-	for i := range b.chunks {
-		score := b.score(queryTokens, i)
-		if score > 0 {
-			scores = append(scores, scored{idx: i, score: score})
+	for _, s := range small {
+		if err := s.delete(); err != nil {
+			return fmt.Errorf("failed to remove merged segment %s: %w", s.id, err)
 		}
 	}
 
-	// Sort by score descending
-	sort.Slice(scores, func(i, j int) bool {
-		return scores[i].score > scores[j].score
-	})
+	b.segments = append(big, newSeg)
+	b.chunkLoc = make(map[string]chunkLocation)
+	for _, seg := range b.segments {
+		b.indexChunkLocations(seg)
+	}
+	b.recomputeGlobalStats()
+
+	return b.saveManifest()
+}
 
-	// Take top K
-	if topK > len(scores) {
-		topK = len(scores)
+// writeNewSegment tokenizes chunks and writes them as the next segment file.
+func (b *BM25) writeNewSegment(chunks []*indexer.CodeChunk) (*segment, error) {
+	if err := os.MkdirAll(b.dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create segment directory: %w", err)
 	}
 
-	results := make([]*SearchResult, topK)
-	for i := 0; i < topK; i++ {
-		results[i] = &SearchResult{
-			Chunk: b.chunks[scores[i].idx],
-			Score: float32(scores[i].score),
+	id := fmt.Sprintf("seg%06d", b.nextSeg)
+	b.nextSeg++
+
+	return buildSegment(b.dir, id, chunks, b.tokenize)
+}
+
+// recomputeGlobalStats sums live doc counts and lengths across every
+// segment. It's a plain arithmetic pass over in-memory docLengths slices
+// (no re-tokenizing), so it's cheap enough to run after every Index/Update.
+func (b *BM25) recomputeGlobalStats() {
+	var totalDocs, totalLen int
+	for _, s := range b.segments {
+		for docID, l := range s.docLengths {
+			if !s.isLive(uint32(docID)) {
+				continue
+			}
+			totalDocs++
+			totalLen += l
 		}
 	}
 
-	return results
+	b.totalDocs = totalDocs
+	if totalDocs > 0 {
+		b.avgDocLen = float64(totalLen) / float64(totalDocs)
+	} else {
+		b.avgDocLen = 0
+	}
+}
+
+func (b *BM25) saveManifest() error {
+	ids := make([]string, len(b.segments))
+	for i, s := range b.segments {
+		ids[i] = s.id
+	}
+
+	m := bm25Manifest{
+		Segments:  ids,
+		TotalDocs: b.totalDocs,
+		AvgDocLen: b.avgDocLen,
+		NextSeg:   b.nextSeg,
+		Version:   bm25ManifestVersion,
+	}
+	m.Checksum = manifestChecksum(m.Segments, m.TotalDocs, m.AvgDocLen, m.NextSeg)
+
+	data, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("failed to encode BM25 manifest: %w", err)
+	}
+
+	if err := os.MkdirAll(b.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create segment directory: %w", err)
+	}
+
+	path := filepath.Join(b.dir, "manifest.json")
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write BM25 manifest: %w", err)
+	}
+	return os.Rename(tmp, path)
 }
 
-// score calculates BM25 score for a document
-func (bm *BM25) score(queryTokens []string, docIdx int) float64 {
-	var score float64
+// HasData reports whether the index has anything on disk or in memory to
+// search. Callers use this to tell an empty-but-valid index (nothing has
+// ever been indexed here) apart from one that just hasn't loaded yet.
+func (b *BM25) HasData() bool {
+	b.ensureLoaded()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.totalDocs > 0
+}
 
-	docLen := float64(bm.docLengths[docIdx])
-	tf := bm.termFreqs[docIdx]
+// Search performs BM25 search across every open segment, combining each
+// posting's local term frequency and doc length with the manifest's global
+// totalDocs/avgDocLen.
+func (b *BM25) Search(query string, topK int) []*SearchResult {
+	b.ensureLoaded()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.totalDocs == 0 {
+		return nil
+	}
+
+	queryTokens := b.tokenize(query)
+	if len(queryTokens) == 0 {
+		return nil
+	}
+
+	type hit struct {
+		seg   *segment
+		docID uint32
+	}
+	scores := make(map[hit]float64)
 
 	for _, term := range queryTokens {
-		termFreq, exists := tf[term]
-		if !exists {
-			continue
+		var docFreq int
+		for _, seg := range b.segments {
+			docFreq += seg.docFreq(term)
 		}
-
-		docFreq := bm.docFreqs[term]
 		if docFreq == 0 {
 			continue
 		}
 
-		// IDF component
-		idf := math.Log((float64(bm.totalDocs)-float64(docFreq)+0.5)/(float64(docFreq)+0.5) + 1)
+		idf := math.Log((float64(b.totalDocs)-float64(docFreq)+0.5)/(float64(docFreq)+0.5) + 1)
 
-		// TF component with length normalization
-		tfNorm := (float64(termFreq) * (bm.k1 + 1)) /
-			(float64(termFreq) + bm.k1*(1-bm.b+bm.b*docLen/bm.avgDocLen))
+		for _, seg := range b.segments {
+			for _, p := range seg.lookupTerm(term) {
+				if !seg.isLive(p.docID) {
+					continue
+				}
 
-		score += idf * tfNorm
+				docLen := float64(seg.docLengths[p.docID])
+				tfNorm := (float64(p.freq) * (b.k1 + 1)) /
+					(float64(p.freq) + b.k1*(1-b.b+b.b*docLen/b.avgDocLen))
+
+				scores[hit{seg: seg, docID: p.docID}] += idf * tfNorm
+			}
+		}
+	}
+
+	type scored struct {
+		hit   hit
+		score float64
+	}
+	results := make([]scored, 0, len(scores))
+	for h, score := range scores {
+		results = append(results, scored{hit: h, score: score})
 	}
 
-	return score
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].score > results[j].score
+	})
+
+	if topK > len(results) {
+		topK = len(results)
+	}
+
+	out := make([]*SearchResult, 0, topK)
+	for _, r := range results[:topK] {
+		chunk, err := r.hit.seg.decodeDoc(r.hit.docID)
+		if err != nil {
+			continue
+		}
+		out = append(out, &SearchResult{Chunk: chunk, Score: float32(r.score)})
+	}
+
+	return out
 }
 
 // tokenize splits text into tokens (identifiers)
@@ -194,4 +533,3 @@ func splitCamelCase(s string) []string {
 func isUpper(b byte) bool {
 	return b >= 'A' && b <= 'Z'
 }
-
@@ -0,0 +1,436 @@
+package retriever
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"go.etcd.io/bbolt"
+
+	"github.com/codementor/codementor/internal/embedding"
+	"github.com/codementor/codementor/internal/indexer"
+)
+
+// metaBucket holds store-wide settings (embedding dimension, provider name)
+// and the per-file bookkeeping keys below, separate from the per-file chunk
+// buckets themselves.
+var metaBucket = []byte("meta")
+
+// BoltStore is a VectorStore backed by a local bbolt database, laid out with
+// one bucket per source file path (chunk ID -> gob-encoded EmbeddedChunk)
+// instead of MemoryStore's single JSON blob. Insert/Delete only touch the
+// keys for the files that actually changed inside one db.Update transaction,
+// so a write is O(changed chunks) instead of MemoryStore's O(everything
+// ever indexed), and bbolt's transaction log means a crash mid-write can't
+// corrupt previously-committed data. The per-file bucket split mirrors how
+// key/value blockchain-node stores shard state by account/namespace rather
+// than keeping one giant table.
+type BoltStore struct {
+	db        *bbolt.DB
+	dimension int
+}
+
+// NewBoltStore opens (creating if necessary) a bbolt database at path and
+// records dimension/provider in the meta bucket.
+func NewBoltStore(path string, dimension int, provider string) (*BoltStore, error) {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create directory: %w", err)
+		}
+	}
+
+	db, err := bbolt.Open(path, 0644, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bolt database: %w", err)
+	}
+
+	err = db.Update(func(tx *bbolt.Tx) error {
+		meta, err := tx.CreateBucketIfNotExists(metaBucket)
+		if err != nil {
+			return err
+		}
+		if err := meta.Put([]byte("dimension"), []byte(fmt.Sprintf("%d", dimension))); err != nil {
+			return err
+		}
+		return meta.Put([]byte("provider"), []byte(provider))
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize meta bucket: %w", err)
+	}
+
+	return &BoltStore{db: db, dimension: dimension}, nil
+}
+
+// fileHashKey and fileOwnerKey namespace the two kinds of bookkeeping
+// entries BoltStore keeps in metaBucket alongside dimension/provider: a
+// file's combined content hash (for skip-if-unchanged re-indexing), and the
+// file bucket a given chunk ID currently lives in (so Delete, which only
+// gets IDs, doesn't have to scan every bucket to find them).
+func fileHashKey(filePath string) []byte { return []byte("filehash:" + filePath) }
+func fileOwnerKey(chunkID string) []byte { return []byte("owner:" + chunkID) }
+
+// Insert adds embedded chunks to the store, grouped into one bucket write
+// per source file.
+func (b *BoltStore) Insert(chunks []*embedding.EmbeddedChunk) error {
+	if len(chunks) == 0 {
+		return nil
+	}
+
+	byFile := make(map[string][]*embedding.EmbeddedChunk)
+	for _, c := range chunks {
+		byFile[c.Chunk.FilePath] = append(byFile[c.Chunk.FilePath], c)
+	}
+
+	return b.db.Update(func(tx *bbolt.Tx) error {
+		meta, err := tx.CreateBucketIfNotExists(metaBucket)
+		if err != nil {
+			return err
+		}
+
+		for filePath, fileChunks := range byFile {
+			bucket, err := tx.CreateBucketIfNotExists([]byte(filePath))
+			if err != nil {
+				return fmt.Errorf("failed to open bucket for %s: %w", filePath, err)
+			}
+
+			for _, c := range fileChunks {
+				data, err := gobEncodeChunk(c)
+				if err != nil {
+					return fmt.Errorf("failed to encode chunk %s: %w", c.Chunk.ID, err)
+				}
+				if err := bucket.Put([]byte(c.Chunk.ID), data); err != nil {
+					return err
+				}
+				if err := meta.Put(fileOwnerKey(c.Chunk.ID), []byte(filePath)); err != nil {
+					return err
+				}
+			}
+
+			if err := meta.Put(fileHashKey(filePath), []byte(combinedFileHash(fileChunks))); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// combinedFileHash hashes the sorted ContentHash of the given chunks into one
+// value. Insert records it for each file's chunks in that one call, so
+// FileHash matches CombinedChunkHash over the whole file only when all of
+// the file's chunks were inserted together; CompareAndSwap and Delete leave
+// FileHash unchanged. There's no single raw-file hash
+// available here since Insert only sees already-chunked, already-embedded
+// data, not the file's original bytes.
+func combinedFileHash(chunks []*embedding.EmbeddedChunk) string {
+	hashes := make([]string, len(chunks))
+	for i, c := range chunks {
+		hashes[i] = c.Chunk.ContentHash
+	}
+	return CombinedChunkHash(hashes)
+}
+
+// CombinedChunkHash hashes a file's chunk ContentHash values into the same
+// value Insert records via FileHash, so callers that re-parse a file (cheap)
+// before re-embedding it (expensive) can compare against FileHash and skip
+// the embedding step entirely when nothing in the file actually changed.
+func CombinedChunkHash(contentHashes []string) string {
+	sorted := append([]string(nil), contentHashes...)
+	sort.Strings(sorted)
+
+	h := sha256.New()
+	for _, hash := range sorted {
+		h.Write([]byte(hash))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// FileHash returns the combined content hash BoltStore last recorded for
+// filePath (see combinedFileHash), and whether one was found. Callers use
+// this to skip re-indexing a file whose chunks haven't changed.
+func (b *BoltStore) FileHash(filePath string) (string, bool) {
+	var hash string
+	var ok bool
+
+	_ = b.db.View(func(tx *bbolt.Tx) error {
+		meta := tx.Bucket(metaBucket)
+		if meta == nil {
+			return nil
+		}
+		if v := meta.Get(fileHashKey(filePath)); v != nil {
+			hash = string(v)
+			ok = true
+		}
+		return nil
+	})
+
+	return hash, ok
+}
+
+// Get returns the chunk currently stored under id, looking up its owning
+// file bucket via the same owner index Delete uses.
+func (b *BoltStore) Get(id string) (*embedding.EmbeddedChunk, bool) {
+	var result *embedding.EmbeddedChunk
+
+	_ = b.db.View(func(tx *bbolt.Tx) error {
+		meta := tx.Bucket(metaBucket)
+		if meta == nil {
+			return nil
+		}
+		filePath := meta.Get(fileOwnerKey(id))
+		if filePath == nil {
+			return nil
+		}
+		bucket := tx.Bucket(filePath)
+		if bucket == nil {
+			return nil
+		}
+		data := bucket.Get([]byte(id))
+		if data == nil {
+			return nil
+		}
+		chunk, err := gobDecodeChunk(data)
+		if err != nil {
+			return nil
+		}
+		result = chunk
+		return nil
+	})
+
+	return result, result != nil
+}
+
+// CompareAndSwap stores new under its own chunk ID in one db.Update
+// transaction only if the chunk's current version there matches
+// expectedVersion (0 meaning "doesn't exist yet"), so two concurrent
+// re-index runs against the same file can't silently clobber each other's
+// embedding.
+func (b *BoltStore) CompareAndSwap(id string, expectedVersion uint64, new *embedding.EmbeddedChunk) error {
+	return b.db.Update(func(tx *bbolt.Tx) error {
+		meta, err := tx.CreateBucketIfNotExists(metaBucket)
+		if err != nil {
+			return err
+		}
+
+		var currentVersion uint64
+		if filePath := meta.Get(fileOwnerKey(id)); filePath != nil {
+			if bucket := tx.Bucket(filePath); bucket != nil {
+				if data := bucket.Get([]byte(id)); data != nil {
+					if existing, err := gobDecodeChunk(data); err == nil {
+						currentVersion = existing.Version
+					}
+				}
+			}
+		}
+
+		if currentVersion != expectedVersion {
+			return ErrVersionConflict
+		}
+
+		new.Version = expectedVersion + 1
+
+		filePath := new.Chunk.FilePath
+		bucket, err := tx.CreateBucketIfNotExists([]byte(filePath))
+		if err != nil {
+			return fmt.Errorf("failed to open bucket for %s: %w", filePath, err)
+		}
+
+		data, err := gobEncodeChunk(new)
+		if err != nil {
+			return fmt.Errorf("failed to encode chunk %s: %w", id, err)
+		}
+		if err := bucket.Put([]byte(id), data); err != nil {
+			return err
+		}
+		return meta.Put(fileOwnerKey(id), []byte(filePath))
+	})
+}
+
+// Search finds similar chunks using cosine similarity, scanning every
+// per-file bucket (everything except metaBucket).
+func (b *BoltStore) Search(queryEmbedding []float32, topK int) ([]*SearchResult, error) {
+	type scoredChunk struct {
+		chunk *indexer.CodeChunk
+		score float32
+	}
+	var scored []scoredChunk
+
+	err := b.db.View(func(tx *bbolt.Tx) error {
+		return tx.ForEach(func(name []byte, bucket *bbolt.Bucket) error {
+			if bytes.Equal(name, metaBucket) {
+				return nil
+			}
+			return bucket.ForEach(func(_, v []byte) error {
+				chunk, err := gobDecodeChunk(v)
+				if err != nil {
+					return err
+				}
+				scored = append(scored, scoredChunk{
+					chunk: chunk.Chunk,
+					score: cosineSimilarity(queryEmbedding, chunk.Embedding),
+				})
+				return nil
+			})
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("search failed: %w", err)
+	}
+
+	sort.Slice(scored, func(i, j int) bool { return scored[i].score > scored[j].score })
+
+	if topK > len(scored) {
+		topK = len(scored)
+	}
+
+	results := make([]*SearchResult, topK)
+	for i := 0; i < topK; i++ {
+		results[i] = &SearchResult{
+			Chunk:    scored[i].chunk,
+			Score:    scored[i].score,
+			Distance: 1 - scored[i].score,
+		}
+	}
+
+	return results, nil
+}
+
+// Delete removes chunks by ID, looking up which file bucket each one lives
+// in via the owner entries Insert recorded in metaBucket.
+func (b *BoltStore) Delete(ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	return b.db.Update(func(tx *bbolt.Tx) error {
+		meta := tx.Bucket(metaBucket)
+		if meta == nil {
+			return nil
+		}
+
+		for _, id := range ids {
+			filePath := meta.Get(fileOwnerKey(id))
+			if filePath == nil {
+				continue
+			}
+
+			if bucket := tx.Bucket(filePath); bucket != nil {
+				if err := bucket.Delete([]byte(id)); err != nil {
+					return err
+				}
+			}
+			if err := meta.Delete(fileOwnerKey(id)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Clear drops every bucket, including metaBucket, and recreates metaBucket
+// empty so the store stays usable afterward.
+func (b *BoltStore) Clear() error {
+	return b.db.Update(func(tx *bbolt.Tx) error {
+		var names [][]byte
+		if err := tx.ForEach(func(name []byte, _ *bbolt.Bucket) error {
+			names = append(names, append([]byte(nil), name...))
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		for _, name := range names {
+			if err := tx.DeleteBucket(name); err != nil {
+				return err
+			}
+		}
+
+		_, err := tx.CreateBucketIfNotExists(metaBucket)
+		return err
+	})
+}
+
+// Count returns the number of stored chunks across every file bucket.
+func (b *BoltStore) Count() int {
+	count := 0
+	_ = b.db.View(func(tx *bbolt.Tx) error {
+		return tx.ForEach(func(name []byte, bucket *bbolt.Bucket) error {
+			if bytes.Equal(name, metaBucket) {
+				return nil
+			}
+			count += bucket.Stats().KeyN
+			return nil
+		})
+	})
+	return count
+}
+
+// All returns every chunk in the store, scanning every per-file bucket
+// (everything except metaBucket), same as Search.
+func (b *BoltStore) All() ([]*embedding.EmbeddedChunk, error) {
+	var chunks []*embedding.EmbeddedChunk
+
+	err := b.db.View(func(tx *bbolt.Tx) error {
+		return tx.ForEach(func(name []byte, bucket *bbolt.Bucket) error {
+			if bytes.Equal(name, metaBucket) {
+				return nil
+			}
+			return bucket.ForEach(func(_, v []byte) error {
+				chunk, err := gobDecodeChunk(v)
+				if err != nil {
+					return err
+				}
+				chunks = append(chunks, chunk)
+				return nil
+			})
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list chunks: %w", err)
+	}
+
+	return chunks, nil
+}
+
+// Close closes the underlying bbolt database.
+func (b *BoltStore) Close() error {
+	return b.db.Close()
+}
+
+// Checkpoint is a no-op: bbolt commits each Update transaction to disk
+// before it returns, so every Insert/Delete is already durable.
+func (b *BoltStore) Checkpoint() error {
+	return nil
+}
+
+// HasData checks if the store has any chunks, matching the
+// QdrantStore.HasData helper.
+func (b *BoltStore) HasData() bool {
+	return b.Count() > 0
+}
+
+// gobEncodeChunk and gobDecodeChunk are BoltStore's on-disk record format,
+// chosen over JSON (MemoryStore's format) because gob is both more compact
+// and faster to decode, and BoltStore pays that decode cost on every Search.
+func gobEncodeChunk(chunk *embedding.EmbeddedChunk) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(chunk); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func gobDecodeChunk(data []byte) (*embedding.EmbeddedChunk, error) {
+	var chunk embedding.EmbeddedChunk
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&chunk); err != nil {
+		return nil, err
+	}
+	return &chunk, nil
+}
@@ -0,0 +1,86 @@
+package retriever
+
+import (
+	"sort"
+
+	"github.com/codementor/codementor/internal/indexer"
+)
+
+// GraphExpander expands a top-K result set with its 1-hop neighbors in the
+// reference graph (calls, selector accesses, and type references GoParser
+// extracts and indexer.BuildGraph resolves into chunk-to-chunk edges), so a
+// "how is X used?" query surfaces X's callers/callees alongside X itself.
+// This mirrors the 1-hop expansion code-intelligence indexers like
+// Zoekt/Sourcegraph do over their reference graphs.
+type GraphExpander struct {
+	graph *indexer.Graph
+}
+
+// NewGraphExpander creates a GraphExpander backed by graph.
+func NewGraphExpander(graph *indexer.Graph) *GraphExpander {
+	return &GraphExpander{graph: graph}
+}
+
+// neighborDecay caps a neighbor's score at half its anchor's, so an
+// expanded-in hit is always re-ranked below whichever result actually
+// matched the query but can still outrank unrelated lower-ranked matches.
+const neighborDecay = 0.5
+
+// Expand pulls in up to budget 1-hop neighbors of results (chunks that
+// anything in results calls, is called by, or references), scoring each
+// neighbor relative to the anchor that pulled it in, then re-sorts the
+// combined set by score. chunkByID materializes a neighbor's full chunk —
+// the graph only stores chunk IDs. A nil GraphExpander (no graph built for
+// this repo yet) or a non-positive budget returns results unchanged.
+func (e *GraphExpander) Expand(results []*SearchResult, chunkByID map[string]*indexer.CodeChunk, budget int) []*SearchResult {
+	if e == nil || e.graph == nil || budget <= 0 {
+		return results
+	}
+
+	seen := make(map[string]bool, len(results)+budget)
+	out := make([]*SearchResult, 0, len(results)+budget)
+	for _, r := range results {
+		seen[r.Chunk.ID] = true
+		out = append(out, r)
+	}
+
+	// Graph edges run from a chunk to what it refers to, so callers (the
+	// reverse direction) are collected here.
+	callers := make(map[string][]string)
+	for from, tos := range e.graph.Edges {
+		for _, to := range tos {
+			callers[to] = append(callers[to], from)
+		}
+	}
+	for _, ids := range callers {
+		sort.Strings(ids)
+	}
+
+	added := 0
+	for _, r := range results {
+		if added >= budget {
+			break
+		}
+		neighbors := append(append([]string(nil), e.graph.Neighbors(r.Chunk.ID)...), callers[r.Chunk.ID]...)
+		for _, nid := range neighbors {
+			if added >= budget {
+				break
+			}
+			if seen[nid] {
+				continue
+			}
+			chunk, ok := chunkByID[nid]
+			if !ok {
+				continue
+			}
+
+			seen[nid] = true
+			out = append(out, &SearchResult{Chunk: chunk, Score: r.Score * neighborDecay})
+			added++
+		}
+	}
+
+	sort.Slice(out, func(i, j int) bool { return out[i].Score > out[j].Score })
+
+	return out
+}
@@ -0,0 +1,467 @@
+package retriever
+
+import (
+	"container/heap"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// HNSW tuning constants (see Malkov & Yashunin, "Efficient and robust
+// approximate nearest neighbor search using Hierarchical Navigable Small
+// World graphs"). M bounds the number of neighbors a node keeps per layer
+// above layer 0; Mmax0 is the (larger) bound at layer 0, where most of the
+// graph's connectivity lives.
+const (
+	hnswDefaultM              = 16
+	hnswDefaultMmax0          = 32
+	hnswDefaultEfConstruction = 200
+	hnswDefaultEfSearch       = 64
+
+	// hnswTombstoneRebuildRatio is the fraction of tombstoned-to-total nodes
+	// that triggers a full rebuild of the graph from its live vectors.
+	hnswTombstoneRebuildRatio = 0.2
+)
+
+// hnswNode is one vector's entry in the graph: its neighbor list per layer,
+// from layer 0 (densest) up to Layer (the highest layer it was promoted to).
+type hnswNode struct {
+	ID         string
+	Vector     []float32
+	Layer      int
+	Neighbors  [][]string
+	Tombstoned bool
+}
+
+// hnswCandidate pairs a node ID with its distance to the query/source vector
+// currently being searched or linked against.
+type hnswCandidate struct {
+	id   string
+	dist float32
+}
+
+// HNSWIndex is an in-process approximate-nearest-neighbor index that
+// MemoryStore keeps alongside its exact chunk map. It is not safe for
+// concurrent use on its own — every method assumes the caller already holds
+// MemoryStore.mu, the same convention checkpointLocked uses.
+type HNSWIndex struct {
+	nodes          map[string]*hnswNode
+	entryPoint     string
+	maxLayer       int
+	m              int
+	mMax0          int
+	efConstruction int
+	efSearch       int
+	mL             float64
+	tombstoneCount int
+	rng            *rand.Rand
+}
+
+// NewHNSWIndex creates an empty index using the package defaults for
+// M/Mmax0/efSearch.
+func NewHNSWIndex() *HNSWIndex {
+	return &HNSWIndex{
+		nodes:          make(map[string]*hnswNode),
+		maxLayer:       -1,
+		m:              hnswDefaultM,
+		mMax0:          hnswDefaultMmax0,
+		efConstruction: hnswDefaultEfConstruction,
+		efSearch:       hnswDefaultEfSearch,
+		mL:             1 / math.Log(hnswDefaultM),
+		rng:            rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// assignLayer draws the layer a freshly inserted node is promoted to, per
+// the paper's exponentially-decaying level distribution.
+func (idx *HNSWIndex) assignLayer() int {
+	// rng.Float64() is in [0, 1); clamp away from 0 so -ln(.) stays finite.
+	r := idx.rng.Float64()
+	if r <= 0 {
+		r = 1e-12
+	}
+	return int(math.Floor(-math.Log(r) * idx.mL))
+}
+
+func (idx *HNSWIndex) distance(a, b []float32) float32 {
+	return 1 - cosineSimilarity(a, b)
+}
+
+// Insert adds vector under id, or — if id is already present — updates its
+// vector in place and clears any tombstone, leaving its existing neighbor
+// links as an approximation until the next rebuild.
+func (idx *HNSWIndex) Insert(id string, vector []float32) {
+	if existing, ok := idx.nodes[id]; ok {
+		if existing.Tombstoned {
+			existing.Tombstoned = false
+			idx.tombstoneCount--
+		}
+		existing.Vector = vector
+		return
+	}
+
+	layer := idx.assignLayer()
+	node := &hnswNode{
+		ID:        id,
+		Vector:    vector,
+		Layer:     layer,
+		Neighbors: make([][]string, layer+1),
+	}
+	idx.nodes[id] = node
+
+	if idx.entryPoint == "" {
+		idx.entryPoint = id
+		idx.maxLayer = layer
+		return
+	}
+
+	ep := idx.entryPoint
+	for lc := idx.maxLayer; lc > layer; lc-- {
+		ep = idx.greedyClosest(ep, vector, lc)
+	}
+
+	for lc := minInt(idx.maxLayer, layer); lc >= 0; lc-- {
+		mMax := idx.m
+		if lc == 0 {
+			mMax = idx.mMax0
+		}
+
+		candidates := idx.searchLayer(vector, ep, idx.efConstruction, lc)
+		neighbors := selectNeighbors(candidates, mMax)
+		node.Neighbors[lc] = neighbors
+		for _, nid := range neighbors {
+			idx.addNeighbor(nid, id, lc, mMax)
+		}
+		if len(candidates) > 0 {
+			ep = candidates[0].id
+		}
+	}
+
+	if layer > idx.maxLayer {
+		idx.maxLayer = layer
+		idx.entryPoint = id
+	}
+}
+
+// Delete tombstones id so Search skips it, then rebuilds the whole graph
+// from its live vectors once tombstones exceed hnswTombstoneRebuildRatio of
+// all nodes.
+func (idx *HNSWIndex) Delete(id string) {
+	node, ok := idx.nodes[id]
+	if !ok || node.Tombstoned {
+		return
+	}
+	node.Tombstoned = true
+	idx.tombstoneCount++
+
+	if idx.entryPoint == id {
+		idx.entryPoint = ""
+		idx.maxLayer = -1
+		for otherID, other := range idx.nodes {
+			if !other.Tombstoned && (idx.entryPoint == "" || other.Layer > idx.maxLayer) {
+				idx.entryPoint = otherID
+				idx.maxLayer = other.Layer
+			}
+		}
+	}
+
+	if len(idx.nodes) > 0 && float64(idx.tombstoneCount)/float64(len(idx.nodes)) > hnswTombstoneRebuildRatio {
+		idx.rebuild()
+	}
+}
+
+// rebuild discards tombstoned nodes and reinserts every live vector into a
+// fresh graph, so deleted chunks stop costing graph space and stale edges
+// don't accumulate forever.
+func (idx *HNSWIndex) rebuild() {
+	type liveVector struct {
+		id     string
+		vector []float32
+	}
+
+	live := make([]liveVector, 0, len(idx.nodes)-idx.tombstoneCount)
+	for id, node := range idx.nodes {
+		if !node.Tombstoned {
+			live = append(live, liveVector{id, node.Vector})
+		}
+	}
+
+	idx.nodes = make(map[string]*hnswNode)
+	idx.entryPoint = ""
+	idx.maxLayer = -1
+	idx.tombstoneCount = 0
+
+	for _, lv := range live {
+		idx.Insert(lv.id, lv.vector)
+	}
+}
+
+// Search returns up to topK candidates ordered nearest-first, by greedily
+// descending from the top layer to layer 1 (a single best candidate per
+// layer) and then running a full efSearch-bounded search on layer 0.
+func (idx *HNSWIndex) Search(query []float32, topK int) []hnswCandidate {
+	if idx.entryPoint == "" {
+		return nil
+	}
+
+	ep := idx.entryPoint
+	for lc := idx.maxLayer; lc > 0; lc-- {
+		ep = idx.greedyClosest(ep, query, lc)
+	}
+
+	ef := idx.efSearch
+	if topK > ef {
+		ef = topK
+	}
+
+	found := idx.searchLayer(query, ep, ef, 0)
+
+	result := make([]hnswCandidate, 0, len(found))
+	for _, c := range found {
+		if node := idx.nodes[c.id]; node != nil && !node.Tombstoned {
+			result = append(result, c)
+		}
+	}
+	if len(result) > topK {
+		result = result[:topK]
+	}
+	return result
+}
+
+// greedyClosest is SEARCH-LAYER with ef=1: starting from entryID, repeatedly
+// hop to whichever neighbor at layer is closer to query than the current
+// best, stopping once no neighbor improves on it.
+func (idx *HNSWIndex) greedyClosest(entryID string, query []float32, layer int) string {
+	entry := idx.nodes[entryID]
+	if entry == nil {
+		return entryID
+	}
+
+	best := entryID
+	bestDist := idx.distance(query, entry.Vector)
+
+	for {
+		node := idx.nodes[best]
+		if node == nil || layer >= len(node.Neighbors) {
+			return best
+		}
+
+		improved := false
+		for _, nbrID := range node.Neighbors[layer] {
+			nbr := idx.nodes[nbrID]
+			if nbr == nil || nbr.Tombstoned {
+				continue
+			}
+			d := idx.distance(query, nbr.Vector)
+			if d < bestDist {
+				bestDist = d
+				best = nbrID
+				improved = true
+			}
+		}
+		if !improved {
+			return best
+		}
+	}
+}
+
+// searchLayer is the standard HNSW SEARCH-LAYER: a min-heap of candidates
+// still to explore and a max-heap holding the current ef-nearest working
+// set, returned sorted nearest-first once exploration can no longer improve
+// the working set.
+func (idx *HNSWIndex) searchLayer(query []float32, entryID string, ef int, layer int) []hnswCandidate {
+	entry := idx.nodes[entryID]
+	if entry == nil {
+		return nil
+	}
+
+	entryDist := idx.distance(query, entry.Vector)
+	visited := map[string]bool{entryID: true}
+
+	candidates := &hnswMinHeap{{id: entryID, dist: entryDist}}
+	heap.Init(candidates)
+	working := &hnswMaxHeap{{id: entryID, dist: entryDist}}
+	heap.Init(working)
+
+	for candidates.Len() > 0 {
+		c := heap.Pop(candidates).(hnswCandidate)
+		if working.Len() >= ef && c.dist > (*working)[0].dist {
+			break
+		}
+
+		node := idx.nodes[c.id]
+		if node == nil || layer >= len(node.Neighbors) {
+			continue
+		}
+
+		for _, nbrID := range node.Neighbors[layer] {
+			if visited[nbrID] {
+				continue
+			}
+			visited[nbrID] = true
+
+			nbr := idx.nodes[nbrID]
+			if nbr == nil || nbr.Tombstoned {
+				continue
+			}
+
+			d := idx.distance(query, nbr.Vector)
+			if working.Len() < ef || d < (*working)[0].dist {
+				heap.Push(candidates, hnswCandidate{id: nbrID, dist: d})
+				heap.Push(working, hnswCandidate{id: nbrID, dist: d})
+				if working.Len() > ef {
+					heap.Pop(working)
+				}
+			}
+		}
+	}
+
+	result := make([]hnswCandidate, working.Len())
+	copy(result, *working)
+	sort.Slice(result, func(i, j int) bool { return result[i].dist < result[j].dist })
+	return result
+}
+
+// addNeighbor links newNeighbor into id's neighbor list at layer, pruning
+// back down to mMax (keeping the nearest ones to id) if that overflows it.
+func (idx *HNSWIndex) addNeighbor(id, newNeighbor string, layer, mMax int) {
+	node := idx.nodes[id]
+	if node == nil {
+		return
+	}
+	for len(node.Neighbors) <= layer {
+		node.Neighbors = append(node.Neighbors, nil)
+	}
+	node.Neighbors[layer] = append(node.Neighbors[layer], newNeighbor)
+
+	if len(node.Neighbors[layer]) <= mMax {
+		return
+	}
+
+	scored := make([]hnswCandidate, 0, len(node.Neighbors[layer]))
+	for _, nid := range node.Neighbors[layer] {
+		nbr := idx.nodes[nid]
+		if nbr == nil {
+			continue
+		}
+		scored = append(scored, hnswCandidate{id: nid, dist: idx.distance(node.Vector, nbr.Vector)})
+	}
+	sort.Slice(scored, func(i, j int) bool { return scored[i].dist < scored[j].dist })
+
+	if len(scored) > mMax {
+		scored = scored[:mMax]
+	}
+	kept := make([]string, len(scored))
+	for i, s := range scored {
+		kept[i] = s.id
+	}
+	node.Neighbors[layer] = kept
+}
+
+// selectNeighbors takes up to mMax of candidates (already sorted
+// nearest-first by searchLayer). This is the simple nearest-M heuristic
+// rather than the paper's diversity-aware heuristic, which is enough for a
+// single-process in-memory index of this scale.
+func selectNeighbors(candidates []hnswCandidate, mMax int) []string {
+	if len(candidates) > mMax {
+		candidates = candidates[:mMax]
+	}
+	neighbors := make([]string, len(candidates))
+	for i, c := range candidates {
+		neighbors[i] = c.id
+	}
+	return neighbors
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// hnswSnapshot is the JSON-persisted form of an HNSWIndex: graph edges only,
+// since the vectors themselves are already persisted once in
+// storeSnapshot.Chunks and are re-attached on restore.
+type hnswSnapshot struct {
+	EntryPoint string             `json:"entry_point"`
+	MaxLayer   int                `json:"max_layer"`
+	Nodes      []hnswNodeSnapshot `json:"nodes"`
+}
+
+type hnswNodeSnapshot struct {
+	ID         string     `json:"id"`
+	Layer      int        `json:"layer"`
+	Neighbors  [][]string `json:"neighbors"`
+	Tombstoned bool       `json:"tombstoned,omitempty"`
+}
+
+// snapshot captures the current graph edges for persistence.
+func (idx *HNSWIndex) snapshot() *hnswSnapshot {
+	snap := &hnswSnapshot{EntryPoint: idx.entryPoint, MaxLayer: idx.maxLayer}
+	for id, node := range idx.nodes {
+		snap.Nodes = append(snap.Nodes, hnswNodeSnapshot{
+			ID:         id,
+			Layer:      node.Layer,
+			Neighbors:  node.Neighbors,
+			Tombstoned: node.Tombstoned,
+		})
+	}
+	return snap
+}
+
+// restore rebuilds the index's node set from a persisted snapshot, pairing
+// each node back up with its vector from the store's chunk map so a restart
+// doesn't have to recompute the graph from scratch.
+func (idx *HNSWIndex) restore(snap *hnswSnapshot, vectors map[string][]float32) {
+	idx.entryPoint = snap.EntryPoint
+	idx.maxLayer = snap.MaxLayer
+	idx.nodes = make(map[string]*hnswNode, len(snap.Nodes))
+	idx.tombstoneCount = 0
+
+	for _, n := range snap.Nodes {
+		idx.nodes[n.ID] = &hnswNode{
+			ID:         n.ID,
+			Vector:     vectors[n.ID],
+			Layer:      n.Layer,
+			Neighbors:  n.Neighbors,
+			Tombstoned: n.Tombstoned,
+		}
+		if n.Tombstoned {
+			idx.tombstoneCount++
+		}
+	}
+}
+
+// hnswMinHeap pops the nearest (smallest-distance) candidate first; used to
+// drive exploration outward from the entry point.
+type hnswMinHeap []hnswCandidate
+
+func (h hnswMinHeap) Len() int            { return len(h) }
+func (h hnswMinHeap) Less(i, j int) bool  { return h[i].dist < h[j].dist }
+func (h hnswMinHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *hnswMinHeap) Push(x interface{}) { *h = append(*h, x.(hnswCandidate)) }
+func (h *hnswMinHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	*h = old[:n-1]
+	return item
+}
+
+// hnswMaxHeap pops the furthest (largest-distance) candidate first; used to
+// evict the worst member once the working set grows past ef.
+type hnswMaxHeap []hnswCandidate
+
+func (h hnswMaxHeap) Len() int            { return len(h) }
+func (h hnswMaxHeap) Less(i, j int) bool  { return h[i].dist > h[j].dist }
+func (h hnswMaxHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *hnswMaxHeap) Push(x interface{}) { *h = append(*h, x.(hnswCandidate)) }
+func (h *hnswMaxHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	*h = old[:n-1]
+	return item
+}
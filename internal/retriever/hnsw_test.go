@@ -0,0 +1,86 @@
+package retriever
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/codementor/codementor/internal/embedding"
+	"github.com/codementor/codementor/internal/indexer"
+)
+
+// randomUnitVector returns a dim-dimensional vector with components drawn
+// from rng, useful as a cheap stand-in for real embeddings since HNSW's
+// approximation quality only depends on relative distances, not on the
+// vectors coming from an actual model.
+func randomUnitVector(rng *rand.Rand, dim int) []float32 {
+	v := make([]float32, dim)
+	for i := range v {
+		v[i] = rng.Float32()*2 - 1
+	}
+	return v
+}
+
+// TestHNSWSearchRecallMatchesBruteForce keeps the brute-force scan honest as
+// ground truth (per the request that introduced HNSWIndex): it inserts a
+// corpus into a MemoryStore, searches it through the normal HNSW-backed
+// Search path, and compares the result against searchBruteForce run
+// directly against the same corpus, asserting the two agree on most of the
+// top-K IDs.
+func TestHNSWSearchRecallMatchesBruteForce(t *testing.T) {
+	const (
+		corpusSize = 300
+		dim        = 32
+		topK       = 10
+		numQueries = 20
+	)
+
+	rng := rand.New(rand.NewSource(1))
+
+	store := NewMemoryStore("")
+	chunks := make([]*embedding.EmbeddedChunk, corpusSize)
+	for i := 0; i < corpusSize; i++ {
+		id := fmt.Sprintf("chunk-%d", i)
+		chunks[i] = &embedding.EmbeddedChunk{
+			Chunk:     &indexer.CodeChunk{ID: id, Name: id},
+			Embedding: randomUnitVector(rng, dim),
+		}
+	}
+	if err := store.Insert(chunks); err != nil {
+		t.Fatalf("Insert: %v", err)
+	}
+
+	var totalOverlap, totalExpected int
+	for q := 0; q < numQueries; q++ {
+		query := randomUnitVector(rng, dim)
+
+		hnswResults, err := store.Search(query, topK)
+		if err != nil {
+			t.Fatalf("Search: %v", err)
+		}
+		bruteResults := store.searchBruteForce(query, topK)
+
+		bruteIDs := make(map[string]bool, len(bruteResults))
+		for _, r := range bruteResults {
+			bruteIDs[r.Chunk.ID] = true
+		}
+
+		overlap := 0
+		for _, r := range hnswResults {
+			if bruteIDs[r.Chunk.ID] {
+				overlap++
+			}
+		}
+
+		totalOverlap += overlap
+		totalExpected += len(bruteResults)
+	}
+
+	recall := float64(totalOverlap) / float64(totalExpected)
+	// hnswDefaultEfSearch (64) against a 300-vector corpus should recover
+	// the true top-10 all but a few times; 0.8 leaves headroom for the
+	// approximation without letting a real regression slide.
+	if recall < 0.8 {
+		t.Fatalf("HNSW recall vs brute-force ground truth too low: %.2f (overlap %d/%d)", recall, totalOverlap, totalExpected)
+	}
+}
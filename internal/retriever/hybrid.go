@@ -2,51 +2,443 @@ package retriever
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"math"
 	"sort"
 
+	"github.com/codementor/codementor/internal/checkpoint"
+	"github.com/codementor/codementor/internal/embedcache"
 	"github.com/codementor/codementor/internal/embedding"
 	"github.com/codementor/codementor/internal/indexer"
 	"github.com/codementor/codementor/internal/llm"
 )
 
+// maxCASRetries bounds how many times TryUpdateChunks retries a chunk whose
+// CompareAndSwap lost a race to a concurrent writer, before giving up on it.
+const maxCASRetries = 5
+
+// defaultRRFK is the Reciprocal Rank Fusion constant used when neither
+// SearchOptions nor a prior AutoTuneWeights run overrides it.
+const defaultRRFK = 60.0
+
+// defaultCandidateMultiplier is how many times topK candidates Search pulls
+// from each of vector/BM25 before fusing, when not overridden.
+const defaultCandidateMultiplier = 3
+
 // HybridRetriever combines vector search with BM25 keyword search
 type HybridRetriever struct {
-	vectorStore  VectorStore
-	bm25         *BM25
-	embedder     *embedding.Embedder
-	vectorWeight float32
-	bm25Weight   float32
+	vectorStore    VectorStore
+	bm25           *BM25
+	trigram        *TrigramIndex
+	embedder       *embedding.Embedder
+	vectorWeight   float32
+	bm25Weight     float32
+	trigramWeight  float32
+	rrfK           float64
+	checkpoint     *checkpoint.State
+	checkpointSize int
+	collection     string // set via SetCollection; scopes tuned-weights persistence
+
+	embedCache *embedcache.Cache // set via SetEmbedCache; queried before embedding in Index/tryUpdateChunk
+	cacheModel string            // model name embedCache's keys are scoped to
+
+	graphExpander *GraphExpander
+	chunkIndex    map[string]*indexer.CodeChunk // chunk ID -> chunk, for materializing graph neighbors
+
+	reranker Reranker // set via SetReranker; used by SearchWithRerank
 }
 
 // NewHybridRetriever creates a new hybrid retriever
-func NewHybridRetriever(store VectorStore, client *llm.Client) *HybridRetriever {
+func NewHybridRetriever(store VectorStore, client llm.Provider) *HybridRetriever {
 	return &HybridRetriever{
-		vectorStore:  store,
-		bm25:         NewBM25(),
-		embedder:     embedding.NewEmbedder(client),
-		vectorWeight: 0.7, // Weight for vector similarity
-		bm25Weight:   0.3, // Weight for BM25
+		vectorStore:   store,
+		bm25:          NewBM25(),
+		trigram:       NewTrigramIndex(),
+		embedder:      embedding.NewEmbedder(client),
+		vectorWeight:  0.7, // Weight for vector similarity
+		bm25Weight:    0.3, // Weight for BM25
+		trigramWeight: 0.2, // Weight for trigram/grep hits in SearchWithGrep
+		rrfK:          defaultRRFK,
+		chunkIndex:    make(map[string]*indexer.CodeChunk),
 	}
 }
 
-// Index indexes chunks for both vector and BM25 search
+// SetCheckpoint enables resumable indexing: chunks already committed in a
+// previous run (matched by content hash) are skipped, and the checkpoint is
+// advanced to disk after every embedded batch of batchSize chunks.
+func (h *HybridRetriever) SetCheckpoint(cp *checkpoint.State, batchSize int) {
+	h.checkpoint = cp
+	h.checkpointSize = batchSize
+}
+
+// SetEmbedCache installs a content-addressed embedding cache, keyed by
+// embedcache.Key(chunk.ContentHash, model): Index, ApplyUpdate, and
+// tryUpdateChunk all route their embedding calls through embedWithCache,
+// which consults it first, so re-indexing a repo where only a few files
+// changed (or a Watcher reverting a file to previously-seen content) skips
+// re-embedding everything else — the same saving internal/pipeline's
+// Pipeline already gets from SetCache. A nil cache (the default) leaves all
+// three methods embedding every chunk, same as before.
+func (h *HybridRetriever) SetEmbedCache(cache *embedcache.Cache, model string) {
+	h.embedCache = cache
+	h.cacheModel = model
+}
+
+// SetGraph installs the reference graph SearchWithGraphExpand uses to pull
+// in a result's 1-hop neighbors (callers/callees/type references). It's
+// typically built once per indexing run with indexer.BuildGraph and
+// persisted via indexer.SaveGraph/LoadGraph.
+func (h *HybridRetriever) SetGraph(graph *indexer.Graph) {
+	h.graphExpander = NewGraphExpander(graph)
+}
+
+// SetBM25Dir relocates the BM25 index to dir instead of the package default
+// of .codementor/bm25, so each collection gets its own on-disk BM25
+// snapshot next to its vector store instead of colliding with every other
+// collection in one shared directory — the same per-collection scoping
+// NewBoltStore/NewObjStore already use for their own on-disk state. Call it
+// before Index, so the first write lands in the new location; any segments
+// already on disk there are picked up lazily on first use.
+func (h *HybridRetriever) SetBM25Dir(dir string) {
+	h.bm25 = NewBM25In(dir)
+}
+
+// HasBM25Data reports whether the BM25 index already has anything indexed,
+// so a caller skipping re-indexing because the vector store already has
+// data (see RAGAgent.IndexRepository) can tell whether it still needs to
+// separately rebuild BM25 — e.g. right after SetBM25Dir points at a
+// directory that hasn't been written to yet.
+func (h *HybridRetriever) HasBM25Data() bool {
+	return h.bm25.HasData()
+}
+
+// SetCollection scopes AutoTuneWeights' persisted output, and
+// LoadTunedWeights' lookup of it, to collection — the same per-collection
+// scoping SetBM25Dir uses for the BM25 index. Call it once, right after
+// construction.
+func (h *HybridRetriever) SetCollection(collection string) {
+	h.collection = collection
+}
+
+// ApplyTunedWeights installs any weights a previous AutoTuneWeights run
+// persisted for this retriever's collection (see SetCollection), so a
+// freshly started process immediately benefits from tuning done by an
+// earlier one instead of falling back to the 0.7/0.3 defaults. It's a no-op
+// if nothing has been tuned yet.
+func (h *HybridRetriever) ApplyTunedWeights() {
+	if h.collection == "" {
+		return
+	}
+	if w, ok := LoadTunedWeights(h.collection); ok {
+		h.vectorWeight = w.VectorWeight
+		h.bm25Weight = w.BM25Weight
+		h.rrfK = w.RRFK
+	}
+}
+
+// SetReranker installs the Reranker SearchWithRerank uses to rescore
+// candidates after RRF fusion. With none installed, SearchWithRerank
+// behaves exactly like Search.
+func (h *HybridRetriever) SetReranker(r Reranker) {
+	h.reranker = r
+}
+
+// indexChunks records chunks in chunkIndex so GraphExpander can materialize
+// a neighbor chunk ID into its full CodeChunk.
+func (h *HybridRetriever) indexChunks(chunks []*indexer.CodeChunk) {
+	for _, c := range chunks {
+		h.chunkIndex[c.ID] = c
+	}
+}
+
+// embedWithCache is EmbedChunks with an embedcache.Cache (see SetEmbedCache)
+// consulted first: chunks whose content hash is already cached skip the
+// embedder entirely, and only cache misses are sent to it, exactly the split
+// internal/pipeline's embedAndStore does for its own batches. With no cache
+// installed, it's EmbedChunks unchanged.
+func (h *HybridRetriever) embedWithCache(ctx context.Context, chunks []*indexer.CodeChunk, progressFn func(current, total int)) ([]*embedding.EmbeddedChunk, error) {
+	if h.embedCache == nil {
+		return h.embedder.EmbedChunks(ctx, chunks, progressFn)
+	}
+
+	hits := make(map[string]*embedding.EmbeddedChunk, len(chunks))
+	var misses []*indexer.CodeChunk
+	for _, c := range chunks {
+		key := embedcache.Key(c.ContentHash, h.cacheModel)
+		if v, ok := h.embedCache.Get(key); ok {
+			hits[c.ID] = &embedding.EmbeddedChunk{Chunk: c, Embedding: v}
+			continue
+		}
+		misses = append(misses, c)
+	}
+
+	// EmbedChunks reports progress relative to misses only (and not at all
+	// once misses is empty), so wrap it to keep reporting current/total
+	// relative to the whole batch, including the hits EmbedChunks never
+	// sees — otherwise a fully-cached batch would leave the caller's
+	// progress bar stuck instead of advancing past the chunks it skipped.
+	var embeddedMisses []*embedding.EmbeddedChunk
+	var err error
+	if len(misses) > 0 {
+		embeddedMisses, err = h.embedder.EmbedChunks(ctx, misses, func(current, _ int) {
+			if progressFn != nil {
+				progressFn(len(hits)+current, len(chunks))
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+	} else if progressFn != nil {
+		progressFn(len(hits), len(chunks))
+	}
+	for _, ec := range embeddedMisses {
+		key := embedcache.Key(ec.Chunk.ContentHash, h.cacheModel)
+		if err := h.embedCache.Put(key, ec.Embedding); err != nil {
+			return nil, fmt.Errorf("failed to write embed cache: %w", err)
+		}
+	}
+
+	embeddedByID := make(map[string]*embedding.EmbeddedChunk, len(hits)+len(embeddedMisses))
+	for id, ec := range hits {
+		embeddedByID[id] = ec
+	}
+	for _, ec := range embeddedMisses {
+		embeddedByID[ec.Chunk.ID] = ec
+	}
+
+	// Preserve chunks' original order, and silently drop any chunk
+	// EmbedChunks tolerated a failure for (same as a cache-less call would).
+	result := make([]*embedding.EmbeddedChunk, 0, len(chunks))
+	for _, c := range chunks {
+		if ec, ok := embeddedByID[c.ID]; ok {
+			result = append(result, ec)
+		}
+	}
+	return result, nil
+}
+
+// Index indexes chunks for both vector and BM25 search. If a checkpoint was
+// installed via SetCheckpoint, already-committed chunks are skipped and
+// progress is persisted after each batch so the run can resume if it's
+// interrupted mid-embed.
 func (h *HybridRetriever) Index(ctx context.Context, chunks []*indexer.CodeChunk, progressFn func(current, total int)) error {
-	// Build BM25 index (fast, no network calls)
+	// Build BM25 and trigram indexes (fast, no network calls)
 	h.bm25.Index(chunks)
+	h.trigram.Index(chunks)
+	h.indexChunks(chunks)
 
-	// Generate embeddings and store (slow, requires LLM)
-	embeddedChunks, err := h.embedder.EmbedChunks(ctx, chunks, progressFn)
-	if err != nil {
-		return err
+	total := len(chunks)
+	pending := chunks
+	done := 0
+
+	if h.checkpoint != nil {
+		pending = pending[:0]
+		for _, c := range chunks {
+			if h.checkpoint.Skip(c.ID, checkpoint.HashContent(c.Content)) {
+				done++
+			} else {
+				pending = append(pending, c)
+			}
+		}
+	}
+
+	batchSize := h.checkpointSize
+	if batchSize <= 0 || batchSize > len(pending) {
+		batchSize = len(pending)
 	}
+	if batchSize == 0 {
+		batchSize = 1
+	}
+
+	for start := 0; start < len(pending); start += batchSize {
+		end := start + batchSize
+		if end > len(pending) {
+			end = len(pending)
+		}
+		batch := pending[start:end]
+
+		committed := done
+		embeddedChunks, err := h.embedWithCache(ctx, batch, func(current, _ int) {
+			if progressFn != nil {
+				progressFn(committed+current, total)
+			}
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := h.vectorStore.Insert(embeddedChunks); err != nil {
+			return err
+		}
+		done += len(embeddedChunks)
 
-	return h.vectorStore.Insert(embeddedChunks)
+		if h.checkpoint != nil {
+			for _, ec := range embeddedChunks {
+				hash := checkpoint.HashContent(ec.Chunk.Content)
+				if err := h.checkpoint.Commit(done, ec.Chunk.ID, hash); err != nil {
+					return fmt.Errorf("failed to persist checkpoint: %w", err)
+				}
+			}
+		}
+	}
+
+	return nil
 }
 
-// Search performs hybrid search combining vector and BM25
-func (h *HybridRetriever) Search(ctx context.Context, query string, topK int) ([]*SearchResult, error) {
+// ApplyUpdate embeds and inserts newChunks and deletes removedIDs from the
+// vector store, then applies the same change to the BM25 index. It's the
+// incremental counterpart to Index, used by a filesystem Watcher to keep
+// both indexes fresh as individual files change instead of reindexing the
+// whole repository.
+func (h *HybridRetriever) ApplyUpdate(ctx context.Context, newChunks []*indexer.CodeChunk, removedIDs []string) error {
+	var removedChunks []*indexer.CodeChunk
+	if len(removedIDs) > 0 {
+		removeSet := make(map[string]bool, len(removedIDs))
+		for _, id := range removedIDs {
+			removeSet[id] = true
+		}
+		for _, c := range h.trigram.chunks {
+			if removeSet[c.ID] {
+				removedChunks = append(removedChunks, c)
+			}
+		}
+
+		if err := h.vectorStore.Delete(removedIDs); err != nil {
+			return fmt.Errorf("failed to delete stale chunks: %w", err)
+		}
+	}
+
+	if len(newChunks) > 0 {
+		embedded, err := h.embedWithCache(ctx, newChunks, nil)
+		if err != nil {
+			return fmt.Errorf("failed to embed changed chunks: %w", err)
+		}
+		if err := h.vectorStore.Insert(embedded); err != nil {
+			return fmt.Errorf("failed to insert changed chunks: %w", err)
+		}
+	}
+
+	h.bm25.Update(newChunks, removedIDs)
+	h.trigram.Update(newChunks, removedChunks)
+	for _, id := range removedIDs {
+		delete(h.chunkIndex, id)
+	}
+	h.indexChunks(newChunks)
+	return nil
+}
+
+// TryUpdateChunks re-indexes chunks one at a time using the GuaranteedUpdate
+// compare-and-swap pattern instead of ApplyUpdate's blanket delete+re-embed:
+// for each chunk it reads the store's current copy, skips re-embedding
+// entirely if ContentHash hasn't changed, and otherwise embeds and writes
+// the update with CompareAndSwap, retrying up to maxCASRetries times if a
+// concurrent writer (e.g. an overlapping POST /index call against the same
+// repo) updated the same chunk first. This lets several re-index runs race
+// safely against one store and skips embedding work for chunks that didn't
+// actually change, which matters when embeddings come from a slow local
+// Ollama or CodeBERT service. It also updates the BM25/trigram/graph
+// indexes for every chunk passed in, same as ApplyUpdate.
+func (h *HybridRetriever) TryUpdateChunks(ctx context.Context, chunks []*indexer.CodeChunk) error {
+	for _, chunk := range chunks {
+		if err := h.tryUpdateChunk(ctx, chunk); err != nil {
+			return fmt.Errorf("failed to update chunk %s: %w", chunk.ID, err)
+		}
+	}
+
+	// Replace each chunk's previous copy (same ID) rather than adding a
+	// second one alongside it.
+	ids := make([]string, len(chunks))
+	for i, c := range chunks {
+		ids[i] = c.ID
+	}
+	h.bm25.Update(chunks, ids)
+	h.trigram.Update(chunks, chunks)
+	h.indexChunks(chunks)
+	return nil
+}
+
+// tryUpdateChunk is the single-chunk CompareAndSwap retry loop TryUpdateChunks
+// runs per chunk.
+func (h *HybridRetriever) tryUpdateChunk(ctx context.Context, chunk *indexer.CodeChunk) error {
+	for attempt := 0; attempt < maxCASRetries; attempt++ {
+		existing, ok := h.vectorStore.Get(chunk.ID)
+		if ok && existing.Chunk.ContentHash == chunk.ContentHash {
+			return nil
+		}
+
+		embedded, err := h.embedWithCache(ctx, []*indexer.CodeChunk{chunk}, nil)
+		if err != nil {
+			return fmt.Errorf("failed to embed chunk: %w", err)
+		}
+		if len(embedded) == 0 {
+			return fmt.Errorf("failed to embed chunk: embedder returned no result")
+		}
+
+		var expectedVersion uint64
+		if ok {
+			expectedVersion = existing.Version
+		}
+
+		err = h.vectorStore.CompareAndSwap(chunk.ID, expectedVersion, embedded[0])
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, ErrVersionConflict) {
+			return err
+		}
+		// Lost the race to a concurrent writer; re-read and retry.
+	}
+
+	return fmt.Errorf("gave up after %d CompareAndSwap conflicts", maxCASRetries)
+}
+
+// SearchOptions overrides Search's weights, RRF constant, and candidate
+// multiplier for a single call. A nil field falls back to the retriever's
+// configured default (h.vectorWeight/h.bm25Weight/h.rrfK/
+// defaultCandidateMultiplier) — Search takes SearchOptions as a variadic
+// parameter, so existing callers that don't need per-call overrides are
+// unaffected.
+type SearchOptions struct {
+	VectorWeight        *float32
+	BM25Weight          *float32
+	RRFK                *float64
+	CandidateMultiplier *int
+}
+
+// resolve fills in any SearchOptions fields left nil with h's defaults.
+func (h *HybridRetriever) resolve(opts []SearchOptions) (vectorWeight, bm25Weight float32, rrfK float64, candidateMultiplier int) {
+	vectorWeight, bm25Weight, rrfK, candidateMultiplier = h.vectorWeight, h.bm25Weight, h.rrfK, defaultCandidateMultiplier
+	if len(opts) == 0 {
+		return
+	}
+
+	o := opts[0]
+	if o.VectorWeight != nil {
+		vectorWeight = *o.VectorWeight
+	}
+	if o.BM25Weight != nil {
+		bm25Weight = *o.BM25Weight
+	}
+	if o.RRFK != nil {
+		rrfK = *o.RRFK
+	}
+	if o.CandidateMultiplier != nil {
+		candidateMultiplier = *o.CandidateMultiplier
+	}
+	return
+}
+
+// Search performs hybrid search combining vector and BM25, fused by
+// Reciprocal Rank Fusion. opts is variadic so a caller can pass a
+// SearchOptions to override the weights/k/candidate multiplier for just
+// this call (see AutoTuneWeights); with none given, Search uses the
+// retriever's configured defaults.
+func (h *HybridRetriever) Search(ctx context.Context, query string, topK int, opts ...SearchOptions) ([]*SearchResult, error) {
+	vectorWeight, bm25Weight, rrfK, candidateMultiplier := h.resolve(opts)
+
 	// Get more candidates from each method, then merge
-	candidateK := topK * 3
+	candidateK := topK * candidateMultiplier
 
 	// Vector search
 	queryEmbedding, err := h.embedder.EmbedChunks(ctx, []*indexer.CodeChunk{{Content: query}}, nil)
@@ -64,7 +456,66 @@ func (h *HybridRetriever) Search(ctx context.Context, query string, topK int) ([
 	bm25Results := h.bm25.Search(query, candidateK)
 
 	// Merge results using Reciprocal Rank Fusion (RRF)
-	return h.mergeResults(vectorResults, bm25Results, topK), nil
+	return h.mergeRanked([]rankedResults{
+		{vectorResults, vectorWeight},
+		{bm25Results, bm25Weight},
+	}, topK, rrfK), nil
+}
+
+// SearchWithGraphExpand performs the same vector+BM25 fusion as Search, then
+// pulls in up to budget 1-hop reference-graph neighbors of the top results
+// (see SetGraph) so a hit's callers/callees/type references ride along —
+// useful for "how is X used?" queries where the right answer often isn't X
+// itself but the code around it. With no graph installed, this is
+// equivalent to Search.
+func (h *HybridRetriever) SearchWithGraphExpand(ctx context.Context, query string, topK, budget int) ([]*SearchResult, error) {
+	results, err := h.Search(ctx, query, topK)
+	if err != nil {
+		return nil, err
+	}
+	return h.graphExpander.Expand(results, h.chunkIndex, budget), nil
+}
+
+// rerankCandidateMultiplier is how many times topK candidates
+// SearchWithRerank pulls from Search before handing them to the reranker —
+// reranking needs a wider net than the final result count to have anything
+// worth reordering.
+const rerankCandidateMultiplier = 4
+
+// maxRerankCandidates caps how many candidates SearchWithRerank will ever
+// hand to a Reranker, regardless of topK*rerankCandidateMultiplier — each
+// candidate costs its own LLM call, so an uncapped topK from a caller would
+// otherwise multiply straight through into an unbounded number of them.
+const maxRerankCandidates = 100
+
+// SearchWithRerank performs the same vector+BM25 fusion as Search over a
+// wider candidate set, then rescores those candidates with the installed
+// Reranker (see SetReranker) and returns the top topK by rerank score. With
+// no Reranker installed, it's equivalent to Search.
+func (h *HybridRetriever) SearchWithRerank(ctx context.Context, query string, topK int) ([]*SearchResult, error) {
+	if h.reranker == nil {
+		return h.Search(ctx, query, topK)
+	}
+
+	candidateCount := topK * rerankCandidateMultiplier
+	if candidateCount > maxRerankCandidates {
+		candidateCount = maxRerankCandidates
+	}
+
+	candidates, err := h.Search(ctx, query, candidateCount)
+	if err != nil {
+		return nil, err
+	}
+
+	reranked, err := h.reranker.Rerank(ctx, query, candidates)
+	if err != nil {
+		return nil, err
+	}
+
+	if topK < len(reranked) {
+		reranked = reranked[:topK]
+	}
+	return reranked, nil
 }
 
 // VectorSearch performs vector-only search
@@ -82,29 +533,78 @@ func (h *HybridRetriever) KeywordSearch(query string, topK int) []*SearchResult
 	return h.bm25.Search(query, topK)
 }
 
-// mergeResults merges vector and BM25 results using Reciprocal Rank Fusion
-func (h *HybridRetriever) mergeResults(vectorResults, bm25Results []*SearchResult, topK int) []*SearchResult {
-	const k = 60.0 // RRF constant
+// GrepSearch performs trigram-accelerated substring/regex search, for
+// queries BM25's tokenizer can't answer: partial identifiers, string
+// literals, and arbitrary regexes.
+func (h *HybridRetriever) GrepSearch(pattern string, topK int) ([]*SearchResult, error) {
+	results, err := h.trigram.Search(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if topK > 0 && topK < len(results) {
+		results = results[:topK]
+	}
+	return results, nil
+}
+
+// SearchWithGrep performs the same vector+BM25 fusion as Search, plus
+// trigram/regex hits for pattern, fused in via the same Reciprocal Rank
+// Fusion so a query that's also a useful grep pattern (an identifier
+// fragment, say) surfaces exact substring matches alongside semantic ones.
+func (h *HybridRetriever) SearchWithGrep(ctx context.Context, query, pattern string, topK int) ([]*SearchResult, error) {
+	candidateK := topK * 3
+
+	queryEmbedding, err := h.embedder.EmbedChunks(ctx, []*indexer.CodeChunk{{Content: query}}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	vectorResults, err := h.vectorStore.Search(queryEmbedding[0].Embedding, candidateK)
+	if err != nil {
+		return nil, err
+	}
+
+	bm25Results := h.bm25.Search(query, candidateK)
 
+	grepResults, err := h.trigram.Search(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if len(grepResults) > candidateK {
+		grepResults = grepResults[:candidateK]
+	}
+
+	return h.mergeRanked([]rankedResults{
+		{vectorResults, h.vectorWeight},
+		{bm25Results, h.bm25Weight},
+		{grepResults, h.trigramWeight},
+	}, topK, h.rrfK), nil
+}
+
+// rankedResults pairs a ranked result list with the weight its ranks should
+// contribute during Reciprocal Rank Fusion.
+type rankedResults struct {
+	results []*SearchResult
+	weight  float32
+}
+
+// mergeRanked fuses any number of ranked result lists using Reciprocal Rank
+// Fusion, weighting each list's contribution by its rankedResults.weight. k
+// is the RRF constant (see SearchOptions.RRFK) that controls how steeply a
+// list's contribution falls off with rank.
+func (h *HybridRetriever) mergeRanked(sources []rankedResults, topK int, k float64) []*SearchResult {
 	// Create a map to store combined scores
 	scoreMap := make(map[string]float32)
 	chunkMap := make(map[string]*indexer.CodeChunk)
 
-	// Add vector results with RRF scores
-	for rank, result := range vectorResults {
-		id := result.Chunk.ID
-		score := h.vectorWeight * float32(1.0/(k+float64(rank+1)))
-		scoreMap[id] += score
-		chunkMap[id] = result.Chunk
-	}
-
-	// Add BM25 results with RRF scores
-	for rank, result := range bm25Results {
-		id := result.Chunk.ID
-		score := h.bm25Weight * float32(1.0/(k+float64(rank+1)))
-		scoreMap[id] += score
-		if _, exists := chunkMap[id]; !exists {
-			chunkMap[id] = result.Chunk
+	for _, source := range sources {
+		for rank, result := range source.results {
+			id := result.Chunk.ID
+			score := source.weight * float32(1.0/(k+float64(rank+1)))
+			scoreMap[id] += score
+			if _, exists := chunkMap[id]; !exists {
+				chunkMap[id] = result.Chunk
+			}
 		}
 	}
 
@@ -145,14 +645,152 @@ func (h *HybridRetriever) SetWeights(vectorWeight, bm25Weight float32) {
 	h.bm25Weight = bm25Weight
 }
 
+// QueryJudgment is a labeled example for AutoTuneWeights: Query is the
+// search string, RelevantChunkIDs the chunk IDs a human (or a trusted
+// offline judge) considers relevant results for it.
+type QueryJudgment struct {
+	Query            string
+	RelevantChunkIDs []string
+}
+
+// candidateRRFKs are the RRF k values AutoTuneWeights grid-searches over,
+// alongside candidateVectorWeights. A smaller k weights top ranks more
+// heavily relative to lower ones; candidateRRFKs brackets defaultRRFK on
+// both sides rather than only refining around it, since the right value is
+// corpus-dependent.
+var candidateRRFKs = []float64{20, 40, defaultRRFK, 80, 100}
+
+// candidateVectorWeights are the vectorWeight values AutoTuneWeights
+// grid-searches over; bm25Weight is always 1-vectorWeight, so the two stay
+// on the same 0-1 scale Search already expects.
+var candidateVectorWeights = []float32{0.1, 0.3, 0.5, 0.7, 0.9}
+
+// queryCandidates is a labeled query's vector and BM25 candidate results,
+// fetched once up front so AutoTuneWeights' grid search only has to re-run
+// the (cheap, local) RRF fusion for each weight/k combination instead of
+// re-embedding and re-searching the query every time — the candidate lists
+// themselves don't depend on vectorWeight/bm25Weight/rrfK at all.
+type queryCandidates struct {
+	judgment      QueryJudgment
+	vectorResults []*SearchResult
+	bm25Results   []*SearchResult
+}
+
+// AutoTuneWeights grid-searches vectorWeight/bm25Weight/RRF-k combinations,
+// scoring each by mean nDCG@topK across labeledQueries, and installs the
+// best-scoring combination (via SetWeights and h.rrfK) as the retriever's
+// new defaults. If h.collection was set via SetCollection, the winning
+// combination is also persisted so a later process picks it up via
+// ApplyTunedWeights instead of starting from the 0.7/0.3 defaults again.
+func (h *HybridRetriever) AutoTuneWeights(ctx context.Context, labeledQueries []QueryJudgment, topK int) (TunedWeights, error) {
+	if len(labeledQueries) == 0 {
+		return TunedWeights{}, fmt.Errorf("no labeled queries to tune against")
+	}
+
+	candidateK := topK * defaultCandidateMultiplier
+	candidates := make([]queryCandidates, len(labeledQueries))
+	for i, q := range labeledQueries {
+		queryEmbedding, err := h.embedder.EmbedChunks(ctx, []*indexer.CodeChunk{{Content: q.Query}}, nil)
+		if err != nil {
+			return TunedWeights{}, fmt.Errorf("failed to embed %q: %w", q.Query, err)
+		}
+		vectorResults, err := h.vectorStore.Search(queryEmbedding[0].Embedding, candidateK)
+		if err != nil {
+			return TunedWeights{}, fmt.Errorf("failed to vector-search %q: %w", q.Query, err)
+		}
+		candidates[i] = queryCandidates{
+			judgment:      q,
+			vectorResults: vectorResults,
+			bm25Results:   h.bm25.Search(q.Query, candidateK),
+		}
+	}
+
+	var best TunedWeights
+	bestSet := false
+
+	for _, vectorWeight := range candidateVectorWeights {
+		bm25Weight := 1 - vectorWeight
+		for _, rrfK := range candidateRRFKs {
+			var total float64
+			for _, c := range candidates {
+				results := h.mergeRanked([]rankedResults{
+					{c.vectorResults, vectorWeight},
+					{c.bm25Results, bm25Weight},
+				}, topK, rrfK)
+				total += ndcgAtK(results, c.judgment.RelevantChunkIDs, topK)
+			}
+			meanNDCG := total / float64(len(candidates))
+
+			if !bestSet || meanNDCG > best.NDCG {
+				best = TunedWeights{VectorWeight: vectorWeight, BM25Weight: bm25Weight, RRFK: rrfK, NDCG: meanNDCG}
+				bestSet = true
+			}
+		}
+	}
+
+	h.vectorWeight = best.VectorWeight
+	h.bm25Weight = best.BM25Weight
+	h.rrfK = best.RRFK
+
+	if h.collection != "" {
+		if err := best.save(h.collection); err != nil {
+			return best, fmt.Errorf("failed to persist tuned weights: %w", err)
+		}
+	}
+
+	return best, nil
+}
+
+// ndcgAtK computes normalized discounted cumulative gain for results against
+// a binary-relevance judgment (a chunk ID is either in relevantIDs or it
+// isn't), considering only the first topK results.
+func ndcgAtK(results []*SearchResult, relevantIDs []string, topK int) float64 {
+	if len(relevantIDs) == 0 {
+		return 0
+	}
+
+	relevant := make(map[string]bool, len(relevantIDs))
+	for _, id := range relevantIDs {
+		relevant[id] = true
+	}
+
+	if topK > len(results) {
+		topK = len(results)
+	}
+
+	var dcg float64
+	for i := 0; i < topK; i++ {
+		if relevant[results[i].Chunk.ID] {
+			dcg += 1 / math.Log2(float64(i+2))
+		}
+	}
+
+	idealHits := len(relevantIDs)
+	if idealHits > topK {
+		idealHits = topK
+	}
+	var idcg float64
+	for i := 0; i < idealHits; i++ {
+		idcg += 1 / math.Log2(float64(i+2))
+	}
+	if idcg == 0 {
+		return 0
+	}
+
+	return dcg / idcg
+}
+
 // GetChunkCount returns the number of indexed chunks
 func (h *HybridRetriever) GetChunkCount() int {
 	return h.vectorStore.Count()
 }
 
-// BuildBM25Index builds only the BM25 index (for when vector data already exists)
+// BuildBM25Index builds the BM25 and trigram indexes (for when vector data
+// already exists)
 func (h *HybridRetriever) BuildBM25Index(chunks []*indexer.CodeChunk) {
 	h.bm25.Index(chunks)
+	h.trigram.Index(chunks)
+	h.indexChunks(chunks)
 }
 
 // Close closes the retriever
@@ -11,40 +11,142 @@ import (
 
 	"github.com/codementor/codementor/internal/embedding"
 	"github.com/codementor/codementor/internal/indexer"
+	"github.com/codementor/codementor/internal/retriever/wal"
 )
 
-// MemoryStore is an in-memory vector store with persistence
+// defaultWALSegmentBytes is the WAL segment size MemoryStore compacts at
+// when VectorConfig.WALSegmentBytes isn't set.
+const defaultWALSegmentBytes = 8 * 1024 * 1024
+
+// MemoryStore is an in-memory vector store with persistence. Every
+// Insert/Delete is first framed and appended to a write-ahead log (see the
+// wal package) before the in-memory map changes, so a process that dies
+// mid-write loses at most that one unflushed call instead of corrupting
+// dataPath the way an interrupted full-map rewrite could.
 type MemoryStore struct {
-	mu       sync.RWMutex
-	chunks   map[string]*embedding.EmbeddedChunk
-	dataPath string
+	mu              sync.RWMutex
+	chunks          map[string]*embedding.EmbeddedChunk
+	index           *HNSWIndex
+	dataPath        string
+	wal             *wal.Writer
+	walPath         string
+	walSegmentBytes int64
+}
+
+// storeSnapshot is the on-disk form of a MemoryStore: the exact chunk map
+// plus the HNSW graph's edges, so a restart doesn't have to re-run
+// HNSWIndex.Insert for every chunk just to get an approximate index back.
+type storeSnapshot struct {
+	Chunks []*embedding.EmbeddedChunk `json:"chunks"`
+	Graph  *hnswSnapshot              `json:"graph,omitempty"`
 }
 
-// NewMemoryStore creates a new in-memory vector store
+// NewMemoryStore creates a new in-memory vector store, using
+// defaultWALSegmentBytes as its WAL compaction threshold.
 func NewMemoryStore(dataPath string) *MemoryStore {
+	return NewMemoryStoreWithWAL(dataPath, defaultWALSegmentBytes)
+}
+
+// NewMemoryStoreWithWAL creates a new in-memory vector store whose WAL
+// compacts into a checkpoint once its segment passes walSegmentBytes (see
+// VectorConfig.WALSegmentBytes). It loads the last checkpoint from dataPath,
+// then replays the WAL on top of it so any Insert/Delete committed after
+// that checkpoint but before a crash is recovered.
+func NewMemoryStoreWithWAL(dataPath string, walSegmentBytes int64) *MemoryStore {
+	if walSegmentBytes <= 0 {
+		walSegmentBytes = defaultWALSegmentBytes
+	}
+
 	store := &MemoryStore{
-		chunks:   make(map[string]*embedding.EmbeddedChunk),
-		dataPath: dataPath,
+		chunks:          make(map[string]*embedding.EmbeddedChunk),
+		index:           NewHNSWIndex(),
+		dataPath:        dataPath,
+		walSegmentBytes: walSegmentBytes,
 	}
 
+	if dataPath == "" {
+		return store
+	}
+
+	store.walPath = dataPath + ".wal"
+
 	// Try to load existing data
-	if dataPath != "" {
-		_ = store.load()
+	_ = store.load()
+
+	if w, err := wal.OpenWriter(store.walPath); err == nil {
+		store.wal = w
+		_ = store.replayWAL()
 	}
 
 	return store
 }
 
+// replayWAL applies every record written since the last checkpoint (the
+// records after the last OpCheckpoint marker, or every record if there
+// isn't one — Checkpoint() normally truncates the segment right after
+// writing that marker, so in the common case there's nothing left to skip).
+func (m *MemoryStore) replayWAL() error {
+	records, err := wal.ReadAll(m.walPath)
+	if err != nil {
+		return err
+	}
+
+	start := 0
+	for i, rec := range records {
+		if rec.Op == wal.OpCheckpoint {
+			start = i + 1
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, rec := range records[start:] {
+		switch rec.Op {
+		case wal.OpInsert:
+			var chunk embedding.EmbeddedChunk
+			if err := json.Unmarshal(rec.Payload, &chunk); err != nil {
+				continue
+			}
+			m.chunks[rec.ChunkID] = &chunk
+			m.index.Insert(rec.ChunkID, chunk.Embedding)
+		case wal.OpDelete:
+			delete(m.chunks, rec.ChunkID)
+			m.index.Delete(rec.ChunkID)
+		}
+	}
+
+	return nil
+}
+
 // Insert adds embedded chunks to the store
 func (m *MemoryStore) Insert(chunks []*embedding.EmbeddedChunk) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	for _, chunk := range chunks {
+		if m.wal != nil {
+			payload, err := json.Marshal(chunk)
+			if err != nil {
+				return fmt.Errorf("failed to marshal chunk for WAL: %w", err)
+			}
+			if err := m.wal.Append(wal.Record{Op: wal.OpInsert, ChunkID: chunk.Chunk.ID, Payload: payload}); err != nil {
+				return fmt.Errorf("failed to append to WAL: %w", err)
+			}
+		}
 		m.chunks[chunk.Chunk.ID] = chunk
+		m.index.Insert(chunk.Chunk.ID, chunk.Embedding)
+	}
+
+	if m.wal != nil {
+		if m.wal.Size() >= m.walSegmentBytes {
+			return m.checkpointLocked()
+		}
+		return nil
 	}
 
-	// Persist if dataPath is set
+	// No WAL (e.g. it failed to open, or dataPath is unset): fall back to
+	// the old behavior of rewriting the whole snapshot on every write.
 	if m.dataPath != "" {
 		return m.save()
 	}
@@ -52,7 +154,63 @@ func (m *MemoryStore) Insert(chunks []*embedding.EmbeddedChunk) error {
 	return nil
 }
 
-// Search finds similar chunks using cosine similarity
+// Get returns the chunk currently stored under id, if any.
+func (m *MemoryStore) Get(id string) (*embedding.EmbeddedChunk, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	c, ok := m.chunks[id]
+	return c, ok
+}
+
+// CompareAndSwap stores new only if id's current version matches
+// expectedVersion (0 meaning "doesn't exist yet"), so two concurrent
+// re-index runs against the same store can't silently clobber each other's
+// writes with a stale embedding. On success it's WAL-logged exactly like
+// Insert.
+func (m *MemoryStore) CompareAndSwap(id string, expectedVersion uint64, new *embedding.EmbeddedChunk) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var currentVersion uint64
+	if existing, ok := m.chunks[id]; ok {
+		currentVersion = existing.Version
+	}
+	if currentVersion != expectedVersion {
+		return ErrVersionConflict
+	}
+
+	new.Version = expectedVersion + 1
+
+	if m.wal != nil {
+		payload, err := json.Marshal(new)
+		if err != nil {
+			return fmt.Errorf("failed to marshal chunk for WAL: %w", err)
+		}
+		if err := m.wal.Append(wal.Record{Op: wal.OpInsert, ChunkID: id, Payload: payload}); err != nil {
+			return fmt.Errorf("failed to append to WAL: %w", err)
+		}
+	}
+
+	m.chunks[id] = new
+	m.index.Insert(id, new.Embedding)
+
+	if m.wal != nil {
+		if m.wal.Size() >= m.walSegmentBytes {
+			return m.checkpointLocked()
+		}
+		return nil
+	}
+
+	if m.dataPath != "" {
+		return m.save()
+	}
+	return nil
+}
+
+// Search finds similar chunks using the HNSW index, falling back to an
+// exact linear scan (also used as ground truth when comparing recall in
+// tests) when the index can't answer — e.g. it hasn't been populated yet,
+// or every candidate it returned turned out to be stale.
 func (m *MemoryStore) Search(queryEmbedding []float32, topK int) ([]*SearchResult, error) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
@@ -61,7 +219,33 @@ func (m *MemoryStore) Search(queryEmbedding []float32, topK int) ([]*SearchResul
 		return nil, nil
 	}
 
-	// Calculate similarity for all chunks
+	candidates := m.index.Search(queryEmbedding, topK)
+	if len(candidates) == 0 {
+		return m.searchBruteForce(queryEmbedding, topK), nil
+	}
+
+	results := make([]*SearchResult, 0, len(candidates))
+	for _, c := range candidates {
+		embeddedChunk, ok := m.chunks[c.id]
+		if !ok {
+			continue
+		}
+		results = append(results, &SearchResult{
+			Chunk:    embeddedChunk.Chunk,
+			Score:    1 - c.dist,
+			Distance: c.dist,
+		})
+	}
+
+	if len(results) == 0 {
+		return m.searchBruteForce(queryEmbedding, topK), nil
+	}
+
+	return results, nil
+}
+
+// searchBruteForce is the original full linear scan over cosineSimilarity.
+func (m *MemoryStore) searchBruteForce(queryEmbedding []float32, topK int) []*SearchResult {
 	type scoredChunk struct {
 		chunk *indexer.CodeChunk
 		score float32
@@ -76,12 +260,10 @@ func (m *MemoryStore) Search(queryEmbedding []float32, topK int) ([]*SearchResul
 		})
 	}
 
-	// Sort by score descending
 	sort.Slice(scored, func(i, j int) bool {
 		return scored[i].score > scored[j].score
 	})
 
-	// Take top K
 	if topK > len(scored) {
 		topK = len(scored)
 	}
@@ -91,11 +273,11 @@ func (m *MemoryStore) Search(queryEmbedding []float32, topK int) ([]*SearchResul
 		results[i] = &SearchResult{
 			Chunk:    scored[i].chunk,
 			Score:    scored[i].score,
-			Distance: 1 - scored[i].score, // Convert similarity to distance
+			Distance: 1 - scored[i].score,
 		}
 	}
 
-	return results, nil
+	return results
 }
 
 // Delete removes chunks by IDs
@@ -104,7 +286,20 @@ func (m *MemoryStore) Delete(ids []string) error {
 	defer m.mu.Unlock()
 
 	for _, id := range ids {
+		if m.wal != nil {
+			if err := m.wal.Append(wal.Record{Op: wal.OpDelete, ChunkID: id}); err != nil {
+				return fmt.Errorf("failed to append to WAL: %w", err)
+			}
+		}
 		delete(m.chunks, id)
+		m.index.Delete(id)
+	}
+
+	if m.wal != nil {
+		if m.wal.Size() >= m.walSegmentBytes {
+			return m.checkpointLocked()
+		}
+		return nil
 	}
 
 	if m.dataPath != "" {
@@ -120,9 +315,18 @@ func (m *MemoryStore) Clear() error {
 	defer m.mu.Unlock()
 
 	m.chunks = make(map[string]*embedding.EmbeddedChunk)
+	m.index = NewHNSWIndex()
+
+	if m.wal != nil {
+		if err := m.wal.Truncate(); err != nil {
+			return fmt.Errorf("failed to truncate WAL: %w", err)
+		}
+	}
 
 	if m.dataPath != "" {
-		return os.Remove(m.dataPath)
+		if err := os.Remove(m.dataPath); err != nil && !os.IsNotExist(err) {
+			return err
+		}
 	}
 
 	return nil
@@ -135,14 +339,67 @@ func (m *MemoryStore) Count() int {
 	return len(m.chunks)
 }
 
-// Close closes the store
+// All returns every chunk currently in the store.
+func (m *MemoryStore) All() ([]*embedding.EmbeddedChunk, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	chunks := make([]*embedding.EmbeddedChunk, 0, len(m.chunks))
+	for _, c := range m.chunks {
+		chunks = append(chunks, c)
+	}
+	return chunks, nil
+}
+
+// Close checkpoints the store (if a WAL is in use) or does a final save
+// (otherwise), then closes the WAL segment.
 func (m *MemoryStore) Close() error {
+	if m.wal != nil {
+		if err := m.Checkpoint(); err != nil {
+			return err
+		}
+		return m.wal.Close()
+	}
+
 	if m.dataPath != "" {
 		return m.save()
 	}
 	return nil
 }
 
+// Checkpoint durably writes the current in-memory state to dataPath, then
+// appends an OpCheckpoint marker and truncates the WAL segment, since every
+// record up to and including that marker is now reflected in dataPath and
+// doesn't need to be replayed again. The API server calls this on graceful
+// session/server shutdown, and Insert/Delete call it automatically once the
+// WAL segment passes walSegmentBytes.
+func (m *MemoryStore) Checkpoint() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.checkpointLocked()
+}
+
+// checkpointLocked is Checkpoint with m.mu already held.
+func (m *MemoryStore) checkpointLocked() error {
+	if m.dataPath != "" {
+		if err := m.save(); err != nil {
+			return err
+		}
+	}
+
+	if m.wal == nil {
+		return nil
+	}
+
+	if err := m.wal.Append(wal.Record{Op: wal.OpCheckpoint}); err != nil {
+		return fmt.Errorf("failed to append checkpoint marker: %w", err)
+	}
+	if err := m.wal.Truncate(); err != nil {
+		return fmt.Errorf("failed to truncate WAL after checkpoint: %w", err)
+	}
+	return nil
+}
+
 // save persists the store to disk
 func (m *MemoryStore) save() error {
 	// Ensure directory exists
@@ -157,7 +414,9 @@ func (m *MemoryStore) save() error {
 		chunks = append(chunks, chunk)
 	}
 
-	data, err := json.Marshal(chunks)
+	snap := storeSnapshot{Chunks: chunks, Graph: m.index.snapshot()}
+
+	data, err := json.Marshal(snap)
 	if err != nil {
 		return fmt.Errorf("failed to marshal data: %w", err)
 	}
@@ -179,13 +438,29 @@ func (m *MemoryStore) load() error {
 		return err
 	}
 
-	var chunks []*embedding.EmbeddedChunk
-	if err := json.Unmarshal(data, &chunks); err != nil {
-		return err
+	var snap storeSnapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		// Fall back to the bare-array format written before the HNSW graph
+		// was persisted alongside the chunks.
+		var chunks []*embedding.EmbeddedChunk
+		if err2 := json.Unmarshal(data, &chunks); err2 != nil {
+			return err
+		}
+		snap.Chunks = chunks
 	}
 
-	for _, chunk := range chunks {
+	vectors := make(map[string][]float32, len(snap.Chunks))
+	for _, chunk := range snap.Chunks {
 		m.chunks[chunk.Chunk.ID] = chunk
+		vectors[chunk.Chunk.ID] = chunk.Embedding
+	}
+
+	if snap.Graph != nil {
+		m.index.restore(snap.Graph, vectors)
+	} else {
+		for _, chunk := range snap.Chunks {
+			m.index.Insert(chunk.Chunk.ID, chunk.Embedding)
+		}
 	}
 
 	return nil
@@ -0,0 +1,34 @@
+//go:build windows
+
+package retriever
+
+import (
+	"fmt"
+	"os"
+)
+
+// mmapData is a read-only view of a segment file. Windows has no portable
+// mmap in the standard library, so this platform falls back to reading the
+// whole segment into the heap instead of mapping it.
+type mmapData struct {
+	data []byte
+}
+
+// mmapFile reads path into memory in full.
+func mmapFile(path string) (mmapData, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return mmapData{}, fmt.Errorf("failed to read segment: %w", err)
+	}
+	return mmapData{data: data}, nil
+}
+
+// Bytes returns the loaded region.
+func (m mmapData) Bytes() []byte {
+	return m.data
+}
+
+// Close is a no-op: there's no mapping to release.
+func (m mmapData) Close() error {
+	return nil
+}
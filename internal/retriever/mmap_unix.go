@@ -0,0 +1,53 @@
+//go:build !windows
+
+package retriever
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// mmapData is a memory-mapped, read-only view of a segment file. On unix
+// platforms it's backed by a real mmap so the OS page cache — not our
+// process's heap — holds segment bytes that aren't actively being read.
+type mmapData struct {
+	data []byte
+}
+
+// mmapFile memory-maps path read-only for its whole length.
+func mmapFile(path string) (mmapData, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return mmapData{}, fmt.Errorf("failed to open segment: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return mmapData{}, fmt.Errorf("failed to stat segment: %w", err)
+	}
+	if info.Size() == 0 {
+		return mmapData{}, fmt.Errorf("segment file %s is empty", path)
+	}
+
+	data, err := syscall.Mmap(int(f.Fd()), 0, int(info.Size()), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return mmapData{}, fmt.Errorf("failed to mmap segment: %w", err)
+	}
+
+	return mmapData{data: data}, nil
+}
+
+// Bytes returns the mapped region.
+func (m mmapData) Bytes() []byte {
+	return m.data
+}
+
+// Close unmaps the region.
+func (m mmapData) Close() error {
+	if m.data == nil {
+		return nil
+	}
+	return syscall.Munmap(m.data)
+}
@@ -0,0 +1,614 @@
+package retriever
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/codementor/codementor/internal/embedding"
+	"github.com/codementor/codementor/internal/indexer"
+)
+
+// ObjectClient is the minimal interface ObjStore needs from an S3-compatible,
+// GCS, or OpenStack Swift object store: put/get/list/delete a blob by key.
+// Mirroring how observability projects like Thanos/Loki offer swappable
+// object clients behind one small interface rather than baking a specific
+// provider SDK into every caller, each provider gets its own ObjectClient
+// implementation and ObjStore itself never imports a provider-specific type.
+type ObjectClient interface {
+	Put(key string, data []byte) error
+	Get(key string) ([]byte, error)
+	List(prefix string) ([]string, error)
+	Delete(key string) error
+}
+
+// defaultObjSegmentSize caps how many chunks go into one segment object
+// before ObjStore flushes it and starts a new one.
+const defaultObjSegmentSize = 500
+
+// objSegment is one flushed segment's manifest entry: its key and the chunk
+// IDs it contains, so ObjStore knows which segment(s) to download to find a
+// given chunk without listing/decoding every segment in the bucket.
+type objSegment struct {
+	Key      string   `json:"key"`
+	ChunkIDs []string `json:"chunk_ids"`
+}
+
+// objManifest is the small JSON index ObjStore keeps next to the segments:
+// segment -> chunk IDs, plus the set of chunk IDs deleted since their
+// segment was written. Segments are immutable once flushed (same as the
+// BM25 segment design in bm25.go), so deletions are recorded as tombstones
+// here instead of rewriting segment content.
+type objManifest struct {
+	Segments  []objSegment `json:"segments"`
+	Tombstone []string     `json:"tombstone"`
+}
+
+// ObjStore is a VectorStore that persists the embedded corpus to an object
+// store as gzipped JSONL segments, keyed "<prefix>/<repo>/<segment>.jsonl.gz",
+// with a "<prefix>/<repo>/manifest.json" sidecar recording which chunk IDs
+// live in which segment. It's built for sharing one already-indexed repo's
+// embeddings across sessions/machines via a bucket instead of re-embedding
+// per session: a second ObjStore pointed at the same bucket/prefix/repo
+// downloads the manifest and segments instead of calling the embedder.
+type ObjStore struct {
+	mu       sync.Mutex
+	client   ObjectClient
+	prefix   string // shared-bucket namespace; lets multiple repos use one bucket
+	repo     string
+	segSize  int
+	manifest objManifest
+	chunkLoc map[string]string // chunk ID -> segment key, from the manifest
+
+	hydrated   bool
+	hydrateErr error
+	segCache   map[string][]*embedding.EmbeddedChunk // segment key -> decoded chunks, filled by hydrate
+
+	pending []*embedding.EmbeddedChunk // buffered chunks not yet flushed to a segment
+}
+
+// NewObjStore creates an ObjStore over client, namespaced under prefix/repo.
+// It doesn't talk to the object store until the first call that reads or
+// writes chunks (see hydrate), so constructing one to point a new session at an
+// already-indexed shared repo is cheap.
+func NewObjStore(client ObjectClient, prefix, repo string) *ObjStore {
+	return &ObjStore{
+		client:   client,
+		prefix:   strings.Trim(prefix, "/"),
+		repo:     repo,
+		segSize:  defaultObjSegmentSize,
+		chunkLoc: make(map[string]string),
+		segCache: make(map[string][]*embedding.EmbeddedChunk),
+	}
+}
+
+// manifestKey and segmentKey build this ObjStore's namespaced object keys.
+func (o *ObjStore) manifestKey() string {
+	return fmt.Sprintf("%s/%s/manifest.json", o.prefix, o.repo)
+}
+
+func (o *ObjStore) segmentKey(segID int) string {
+	return fmt.Sprintf("%s/%s/segment-%06d.jsonl.gz", o.prefix, o.repo, segID)
+}
+
+// hydrate lazily downloads the manifest (and, the first time it's needed,
+// every segment it references) so Search/Count see the full shared corpus
+// without every ObjStore paying that cost at construction time. It's safe
+// to call repeatedly and concurrently; only the first call does any network
+// I/O. It takes o.mu itself for its full duration, so two first-use callers
+// racing (e.g. a Get and a Search against a freshly constructed ObjStore)
+// can't both observe o.hydrated == false and both write o.chunkLoc/o.segCache
+// at once.
+func (o *ObjStore) hydrate() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.hydrateLocked()
+}
+
+// hydrateLocked is hydrate's body, for callers that already hold o.mu
+// (Insert/Delete).
+func (o *ObjStore) hydrateLocked() error {
+	if o.hydrated {
+		return o.hydrateErr
+	}
+	o.hydrated = true
+
+	data, err := o.client.Get(o.manifestKey())
+	if err != nil {
+		// No manifest yet just means nothing has been flushed to this
+		// repo/prefix yet, not an error.
+		return nil
+	}
+
+	var m objManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		o.hydrateErr = fmt.Errorf("failed to parse manifest: %w", err)
+		return o.hydrateErr
+	}
+	o.manifest = m
+
+	for _, seg := range m.Segments {
+		chunks, err := o.downloadSegment(seg.Key)
+		if err != nil {
+			o.hydrateErr = fmt.Errorf("failed to download segment %s: %w", seg.Key, err)
+			return o.hydrateErr
+		}
+		o.segCache[seg.Key] = chunks
+		for _, c := range chunks {
+			o.chunkLoc[c.Chunk.ID] = seg.Key
+		}
+	}
+
+	return nil
+}
+
+// downloadSegment fetches and decodes a gzipped JSONL segment.
+func (o *ObjStore) downloadSegment(key string) ([]*embedding.EmbeddedChunk, error) {
+	raw, err := o.client.Get(key)
+	if err != nil {
+		return nil, err
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open gzip stream: %w", err)
+	}
+	defer gz.Close()
+
+	var chunks []*embedding.EmbeddedChunk
+	dec := json.NewDecoder(gz)
+	for dec.More() {
+		var c embedding.EmbeddedChunk
+		if err := dec.Decode(&c); err != nil {
+			return nil, fmt.Errorf("failed to decode segment record: %w", err)
+		}
+		chunks = append(chunks, &c)
+	}
+
+	return chunks, nil
+}
+
+// Insert buffers chunks and flushes full defaultObjSegmentSize-sized
+// segments to the object store as they fill up, each as a single gzipped
+// JSONL blob uploaded with one Put (so a partially-uploaded segment, if the
+// process dies mid-write, is simply never referenced by the manifest PUT
+// that follows it).
+func (o *ObjStore) Insert(chunks []*embedding.EmbeddedChunk) error {
+	if len(chunks) == 0 {
+		return nil
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if err := o.hydrateLocked(); err != nil {
+		return err
+	}
+
+	o.pending = append(o.pending, chunks...)
+
+	for len(o.pending) >= o.segSize {
+		if err := o.flushSegment(o.pending[:o.segSize]); err != nil {
+			return err
+		}
+		o.pending = o.pending[o.segSize:]
+	}
+
+	return nil
+}
+
+// Get returns the chunk currently stored under id, hydrating the manifest
+// and its segments first if this is the first read since construction. It
+// checks pending (not yet flushed) chunks first since those are the most
+// recently written.
+func (o *ObjStore) Get(id string) (*embedding.EmbeddedChunk, bool) {
+	if err := o.hydrate(); err != nil {
+		return nil, false
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for _, c := range o.pending {
+		if c.Chunk.ID == id {
+			return c, true
+		}
+	}
+
+	segKey, ok := o.chunkLoc[id]
+	if !ok {
+		return nil, false
+	}
+	for _, c := range o.segCache[segKey] {
+		if c.Chunk.ID == id {
+			return c, true
+		}
+	}
+	return nil, false
+}
+
+// CompareAndSwap stores new under id only if its current version (0 if id
+// isn't stored anywhere yet) matches expectedVersion. An update to a chunk
+// still buffered in pending just replaces it there; an update to a chunk
+// already flushed to a segment rewrites that whole segment via
+// ReplaceSegment, since segments are otherwise immutable once flushed.
+func (o *ObjStore) CompareAndSwap(id string, expectedVersion uint64, new *embedding.EmbeddedChunk) error {
+	if err := o.hydrate(); err != nil {
+		return err
+	}
+
+	o.mu.Lock()
+
+	for i, c := range o.pending {
+		if c.Chunk.ID == id {
+			if c.Version != expectedVersion {
+				o.mu.Unlock()
+				return ErrVersionConflict
+			}
+			new.Version = expectedVersion + 1
+			o.pending[i] = new
+			o.mu.Unlock()
+			return nil
+		}
+	}
+
+	segKey, ok := o.chunkLoc[id]
+	if !ok {
+		if expectedVersion != 0 {
+			o.mu.Unlock()
+			return ErrVersionConflict
+		}
+		new.Version = 1
+		o.pending = append(o.pending, new)
+		o.mu.Unlock()
+		return nil
+	}
+
+	segChunks := append([]*embedding.EmbeddedChunk(nil), o.segCache[segKey]...)
+	found := false
+	for i, c := range segChunks {
+		if c.Chunk.ID == id {
+			if c.Version != expectedVersion {
+				o.mu.Unlock()
+				return ErrVersionConflict
+			}
+			new.Version = expectedVersion + 1
+			segChunks[i] = new
+			found = true
+			break
+		}
+	}
+	if !found {
+		o.mu.Unlock()
+		return fmt.Errorf("chunk %s missing from its indexed segment %s", id, segKey)
+	}
+
+	// Write the replacement segment without releasing o.mu between the
+	// version check above and the write: releasing it here is exactly the
+	// check-then-act race QdrantStore.CompareAndSwap had (see casMu there) —
+	// two concurrent callers could otherwise both pass the check against the
+	// same pre-update segChunks and the second would silently clobber the
+	// first's write.
+	defer o.mu.Unlock()
+	return o.replaceSegmentLocked(segKey, segChunks)
+}
+
+// flushSegment uploads one segment object then updates and re-uploads the
+// manifest. Each of those two PUTs is atomic on its own key (an object
+// store either serves the old bytes or the new ones for a given key, never
+// a mix), so a crash between them leaves either no reference to the new
+// segment (safe, it'll just be re-flushed) or a manifest pointing at a
+// segment that's already fully written.
+func (o *ObjStore) flushSegment(chunks []*embedding.EmbeddedChunk) error {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	enc := json.NewEncoder(gz)
+	for _, c := range chunks {
+		if err := enc.Encode(c); err != nil {
+			return fmt.Errorf("failed to encode chunk %s: %w", c.Chunk.ID, err)
+		}
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("failed to close gzip stream: %w", err)
+	}
+
+	key := o.segmentKey(len(o.manifest.Segments))
+	if err := o.client.Put(key, buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to upload segment: %w", err)
+	}
+
+	ids := make([]string, len(chunks))
+	for i, c := range chunks {
+		ids[i] = c.Chunk.ID
+		o.chunkLoc[c.Chunk.ID] = key
+	}
+	o.segCache[key] = chunks
+	o.manifest.Segments = append(o.manifest.Segments, objSegment{Key: key, ChunkIDs: ids})
+
+	return o.saveManifest()
+}
+
+// saveManifest re-uploads the manifest object. It must be called with o.mu
+// held.
+func (o *ObjStore) saveManifest() error {
+	data, err := json.Marshal(o.manifest)
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	if err := o.client.Put(o.manifestKey(), data); err != nil {
+		return fmt.Errorf("failed to upload manifest: %w", err)
+	}
+	return nil
+}
+
+// ReplaceSegment atomically swaps a segment's content: it overwrites
+// segmentKey's object with chunks gzip-encoded, then updates the manifest's
+// ChunkIDs for that entry. Because a single object key's PUT is atomic,
+// concurrent readers either see the whole old segment or the whole new one.
+// A future compaction pass (merging small segments, dropping tombstoned
+// records permanently) would build on the same primitive.
+func (o *ObjStore) ReplaceSegment(segmentKey string, chunks []*embedding.EmbeddedChunk) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.replaceSegmentLocked(segmentKey, chunks)
+}
+
+// replaceSegmentLocked is ReplaceSegment's body, for callers that already
+// hold o.mu (CompareAndSwap, so its version check and the write it guards
+// happen inside one critical section).
+func (o *ObjStore) replaceSegmentLocked(segmentKey string, chunks []*embedding.EmbeddedChunk) error {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	enc := json.NewEncoder(gz)
+	for _, c := range chunks {
+		if err := enc.Encode(c); err != nil {
+			return fmt.Errorf("failed to encode chunk %s: %w", c.Chunk.ID, err)
+		}
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("failed to close gzip stream: %w", err)
+	}
+
+	if err := o.client.Put(segmentKey, buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to upload replacement segment: %w", err)
+	}
+
+	ids := make([]string, len(chunks))
+	for i, c := range chunks {
+		ids[i] = c.Chunk.ID
+		o.chunkLoc[c.Chunk.ID] = segmentKey
+	}
+	o.segCache[segmentKey] = chunks
+
+	for i, seg := range o.manifest.Segments {
+		if seg.Key == segmentKey {
+			o.manifest.Segments[i].ChunkIDs = ids
+			break
+		}
+	}
+
+	return o.saveManifest()
+}
+
+// Search hydrates the manifest and segments on first call, then scores
+// every chunk (hydrated plus any still-buffered pending chunks) by cosine
+// similarity against queryEmbedding.
+func (o *ObjStore) Search(queryEmbedding []float32, topK int) ([]*SearchResult, error) {
+	if err := o.hydrate(); err != nil {
+		return nil, err
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	tombstoned := make(map[string]bool, len(o.manifest.Tombstone))
+	for _, id := range o.manifest.Tombstone {
+		tombstoned[id] = true
+	}
+
+	type scoredChunk struct {
+		chunk *indexer.CodeChunk
+		score float32
+	}
+	var scored []scoredChunk
+
+	score := func(c *embedding.EmbeddedChunk) {
+		if tombstoned[c.Chunk.ID] {
+			return
+		}
+		scored = append(scored, scoredChunk{
+			chunk: c.Chunk,
+			score: cosineSimilarity(queryEmbedding, c.Embedding),
+		})
+	}
+
+	for _, chunks := range o.segCache {
+		for _, c := range chunks {
+			score(c)
+		}
+	}
+	for _, c := range o.pending {
+		score(c)
+	}
+
+	sort.Slice(scored, func(i, j int) bool { return scored[i].score > scored[j].score })
+
+	if topK > len(scored) {
+		topK = len(scored)
+	}
+
+	results := make([]*SearchResult, topK)
+	for i := 0; i < topK; i++ {
+		results[i] = &SearchResult{
+			Chunk:    scored[i].chunk,
+			Score:    scored[i].score,
+			Distance: 1 - scored[i].score,
+		}
+	}
+
+	return results, nil
+}
+
+// Delete tombstones the given chunk IDs: segments are immutable once
+// flushed, so rather than rewrite a segment to drop a handful of records,
+// Delete records them in the manifest's Tombstone list and Search/Count
+// filter them out. A future compaction pass (see ReplaceSegment) is where
+// tombstoned records would actually be dropped from segment content.
+func (o *ObjStore) Delete(ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if err := o.hydrateLocked(); err != nil {
+		return err
+	}
+
+	existing := make(map[string]bool, len(o.manifest.Tombstone))
+	for _, id := range o.manifest.Tombstone {
+		existing[id] = true
+	}
+	for _, id := range ids {
+		if !existing[id] {
+			o.manifest.Tombstone = append(o.manifest.Tombstone, id)
+			existing[id] = true
+		}
+	}
+
+	filtered := o.pending[:0]
+	removeSet := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		removeSet[id] = true
+	}
+	for _, c := range o.pending {
+		if !removeSet[c.Chunk.ID] {
+			filtered = append(filtered, c)
+		}
+	}
+	o.pending = filtered
+
+	return o.saveManifest()
+}
+
+// Clear deletes every segment and the manifest for this repo/prefix, and
+// resets all in-memory state.
+func (o *ObjStore) Clear() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	keys, err := o.client.List(fmt.Sprintf("%s/%s/", o.prefix, o.repo))
+	if err != nil {
+		return fmt.Errorf("failed to list objects: %w", err)
+	}
+	for _, key := range keys {
+		if err := o.client.Delete(key); err != nil {
+			return fmt.Errorf("failed to delete %s: %w", key, err)
+		}
+	}
+
+	o.manifest = objManifest{}
+	o.chunkLoc = make(map[string]string)
+	o.segCache = make(map[string][]*embedding.EmbeddedChunk)
+	o.pending = nil
+	o.hydrated = true
+	o.hydrateErr = nil
+
+	return nil
+}
+
+// Count returns the number of live (non-tombstoned) chunks. It triggers the
+// same lazy hydrate as Search, which is what lets handleCreateSession in
+// internal/api point a new session at an already-indexed shared repo: its
+// IndexRepository "skip re-indexing" check calls Count() before deciding
+// whether to re-embed anything.
+func (o *ObjStore) Count() int {
+	if err := o.hydrate(); err != nil {
+		return 0
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	tombstoned := make(map[string]bool, len(o.manifest.Tombstone))
+	for _, id := range o.manifest.Tombstone {
+		tombstoned[id] = true
+	}
+
+	count := 0
+	for _, c := range o.pending {
+		if !tombstoned[c.Chunk.ID] {
+			count++
+		}
+	}
+	for id := range o.chunkLoc {
+		if !tombstoned[id] {
+			count++
+		}
+	}
+	return count
+}
+
+// All returns every live (non-tombstoned) chunk, hydrating the manifest and
+// its segments first if this is the first read since construction.
+func (o *ObjStore) All() ([]*embedding.EmbeddedChunk, error) {
+	if err := o.hydrate(); err != nil {
+		return nil, err
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	tombstoned := make(map[string]bool, len(o.manifest.Tombstone))
+	for _, id := range o.manifest.Tombstone {
+		tombstoned[id] = true
+	}
+
+	var chunks []*embedding.EmbeddedChunk
+	for _, segChunks := range o.segCache {
+		for _, c := range segChunks {
+			if !tombstoned[c.Chunk.ID] {
+				chunks = append(chunks, c)
+			}
+		}
+	}
+	for _, c := range o.pending {
+		if !tombstoned[c.Chunk.ID] {
+			chunks = append(chunks, c)
+		}
+	}
+
+	return chunks, nil
+}
+
+// Close flushes any buffered chunks that haven't reached a full segment yet,
+// so a session's last partial batch isn't lost.
+func (o *ObjStore) Close() error {
+	return o.Checkpoint()
+}
+
+// Checkpoint flushes any chunks buffered in pending as an (undersized)
+// segment, so they're durable in the object store even though they haven't
+// reached defaultObjSegmentSize yet.
+func (o *ObjStore) Checkpoint() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if len(o.pending) == 0 {
+		return nil
+	}
+
+	chunks := o.pending
+	o.pending = nil
+	return o.flushSegment(chunks)
+}
+
+// HasData reports whether this repo/prefix has any indexed chunks.
+func (o *ObjStore) HasData() bool {
+	return o.Count() > 0
+}
@@ -0,0 +1,183 @@
+package retriever
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// httpObjectClient is the shared plumbing behind S3Client, GCSClient, and
+// SwiftClient: all three expose a bucket as "GET/PUT/DELETE a key under a
+// base URL" over plain net/http, the same style QdrantStore already uses
+// for talking to its backend instead of importing a provider SDK. Request
+// signing is deliberately out of scope here (AWS SigV4, GCS OAuth2, Swift
+// Keystone tokens all need machinery this tree has no dependency to vendor);
+// accessKey/secretKey are sent as HTTP Basic Auth, which every one of these
+// object stores accepts when fronted by a compatible gateway (e.g. Minio,
+// fake-gcs-server, a Swift proxy with basic-auth middleware) even though
+// it's not how their production endpoints authenticate by default.
+type httpObjectClient struct {
+	baseURL    string // scheme://host[:port]/bucket, no trailing slash
+	accessKey  string
+	secretKey  string
+	httpClient *http.Client
+}
+
+func newHTTPObjectClient(baseURL, accessKey, secretKey string) *httpObjectClient {
+	return &httpObjectClient{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		accessKey:  accessKey,
+		secretKey:  secretKey,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (c *httpObjectClient) objectURL(key string) string {
+	return fmt.Sprintf("%s/%s", c.baseURL, url.PathEscape(key))
+}
+
+func (c *httpObjectClient) do(req *http.Request) (*http.Response, error) {
+	if c.accessKey != "" {
+		req.SetBasicAuth(c.accessKey, c.secretKey)
+	}
+	return c.httpClient.Do(req)
+}
+
+func (c *httpObjectClient) Put(key string, data []byte) error {
+	req, err := http.NewRequest(http.MethodPut, c.objectURL(key), bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	resp, err := c.do(req)
+	if err != nil {
+		return fmt.Errorf("put %s failed: %w", key, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("put %s failed: %s: %s", key, resp.Status, string(body))
+	}
+	return nil
+}
+
+func (c *httpObjectClient) Get(key string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, c.objectURL(key), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("get %s failed: %w", key, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("object %s not found", key)
+	}
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("get %s failed: %s: %s", key, resp.Status, string(body))
+	}
+
+	return io.ReadAll(resp.Body)
+}
+
+func (c *httpObjectClient) Delete(key string) error {
+	req, err := http.NewRequest(http.MethodDelete, c.objectURL(key), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return fmt.Errorf("delete %s failed: %w", key, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 && resp.StatusCode != http.StatusNotFound {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("delete %s failed: %s: %s", key, resp.Status, string(body))
+	}
+	return nil
+}
+
+// List lists keys under prefix using the S3-style ListObjectsV2 query
+// convention (?list-type=2&prefix=...), which Minio, most GCS-compatible
+// gateways, and Swift-with-an-S3-shim all understand.
+func (c *httpObjectClient) List(prefix string) ([]string, error) {
+	u := fmt.Sprintf("%s?list-type=2&prefix=%s", c.baseURL, url.QueryEscape(prefix))
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("list %s failed: %w", prefix, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("list %s failed: %s: %s", prefix, resp.Status, string(body))
+	}
+
+	var listResp struct {
+		Contents []struct {
+			Key string `xml:"Key"`
+		} `xml:"Contents"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&listResp); err != nil {
+		return nil, fmt.Errorf("failed to decode list response: %w", err)
+	}
+
+	keys := make([]string, len(listResp.Contents))
+	for i, obj := range listResp.Contents {
+		keys[i] = obj.Key
+	}
+	return keys, nil
+}
+
+// S3Client is an ObjectClient for S3-compatible stores (AWS S3, Minio,
+// Ceph RGW), addressed path-style: https://endpoint/bucket/key.
+type S3Client struct{ *httpObjectClient }
+
+// NewS3Client builds an S3Client. region is accepted for interface parity
+// with a real SigV4 signer (which needs it to compute the signature) even
+// though this simplified Basic-Auth client doesn't use it.
+func NewS3Client(endpoint, bucket, region, accessKey, secretKey string) *S3Client {
+	_ = region
+	return &S3Client{newHTTPObjectClient(fmt.Sprintf("%s/%s", strings.TrimRight(endpoint, "/"), bucket), accessKey, secretKey)}
+}
+
+// GCSClient is an ObjectClient for Google Cloud Storage (or a GCS-compatible
+// gateway), addressed the same path-style way as S3Client.
+type GCSClient struct{ *httpObjectClient }
+
+// NewGCSClient builds a GCSClient. endpoint defaults to GCS's JSON API host
+// when empty, so a caller only has to override it for a local fake-gcs-server.
+func NewGCSClient(endpoint, bucket, accessKey, secretKey string) *GCSClient {
+	if endpoint == "" {
+		endpoint = "https://storage.googleapis.com"
+	}
+	return &GCSClient{newHTTPObjectClient(fmt.Sprintf("%s/%s", strings.TrimRight(endpoint, "/"), bucket), accessKey, secretKey)}
+}
+
+// SwiftClient is an ObjectClient for an OpenStack Swift container, addressed
+// as https://endpoint/container/key (Swift calls buckets "containers").
+type SwiftClient struct{ *httpObjectClient }
+
+// NewSwiftClient builds a SwiftClient over a Swift container.
+func NewSwiftClient(endpoint, container, accessKey, secretKey string) *SwiftClient {
+	return &SwiftClient{newHTTPObjectClient(fmt.Sprintf("%s/%s", strings.TrimRight(endpoint, "/"), container), accessKey, secretKey)}
+}
+
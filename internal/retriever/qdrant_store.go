@@ -4,8 +4,10 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"hash/fnv"
 	"io"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/codementor/codementor/internal/embedding"
@@ -18,6 +20,13 @@ type QdrantStore struct {
 	collection string
 	dimension  int
 	httpClient *http.Client
+
+	// casMu serializes CompareAndSwap's read-then-write against Qdrant's
+	// points API, which has no native compare-and-swap of its own — without
+	// it, two concurrent callers could both read the same current version,
+	// both pass the check, and both Upsert, silently losing one writer's
+	// update instead of one of them getting ErrVersionConflict.
+	casMu sync.Mutex
 }
 
 // NewQdrantStore creates a new Qdrant vector store
@@ -80,8 +89,26 @@ func (q *QdrantStore) ensureCollection() error {
 	return nil
 }
 
-// Insert adds embedded chunks to Qdrant
+// pointID derives a stable numeric Qdrant point ID from our string chunk ID.
+// Qdrant requires points to be numeric or UUID, so we can't use chunk.ID
+// directly, but hashing it deterministically means re-indexing the same
+// chunk always upserts the same point instead of appending a duplicate.
+func pointID(chunkID string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(chunkID))
+	return h.Sum64()
+}
+
+// Insert adds embedded chunks to Qdrant. It's a thin alias for Upsert, kept
+// so QdrantStore still satisfies VectorStore's Insert method.
 func (q *QdrantStore) Insert(chunks []*embedding.EmbeddedChunk) error {
+	return q.Upsert(chunks)
+}
+
+// Upsert adds or replaces embedded chunks in Qdrant, keyed by the chunk's
+// stable ID so re-embedding an unchanged chunk overwrites its existing point
+// instead of creating a duplicate.
+func (q *QdrantStore) Upsert(chunks []*embedding.EmbeddedChunk) error {
 	if len(chunks) == 0 {
 		return nil
 	}
@@ -102,10 +129,12 @@ func (q *QdrantStore) Insert(chunks []*embedding.EmbeddedChunk) error {
 			"doc_comment": chunk.Chunk.DocComment,
 			"parent_name": chunk.Chunk.ParentName,
 			"content":     chunk.Chunk.Content,
+			"references":  chunk.Chunk.References,
+			"version":     chunk.Version,
 		}
 
 		points[i] = map[string]interface{}{
-			"id":      i + 1, // Qdrant requires numeric or UUID ids
+			"id":      pointID(chunk.Chunk.ID),
 			"vector":  chunk.Embedding,
 			"payload": payload,
 		}
@@ -197,6 +226,7 @@ func (q *QdrantStore) Search(queryEmbedding []float32, topK int) ([]*SearchResul
 			DocComment: getString(r.Payload, "doc_comment"),
 			ParentName: getString(r.Payload, "parent_name"),
 			Content:    getString(r.Payload, "content"),
+			References: getReferences(r.Payload),
 		}
 
 		results[i] = &SearchResult{
@@ -209,11 +239,121 @@ func (q *QdrantStore) Search(queryEmbedding []float32, topK int) ([]*SearchResul
 	return results, nil
 }
 
-// Delete removes chunks by IDs
+// Get retrieves the chunk currently stored under id via Qdrant's
+// single-point retrieve API, returning false if no point exists for it yet.
+func (q *QdrantStore) Get(id string) (*embedding.EmbeddedChunk, bool) {
+	resp, err := q.httpClient.Get(fmt.Sprintf("%s/collections/%s/points/%d", q.host, q.collection, pointID(id)))
+	if err != nil {
+		return nil, false
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, false
+	}
+
+	var pointResp struct {
+		Result struct {
+			Vector  []float32              `json:"vector"`
+			Payload map[string]interface{} `json:"payload"`
+		} `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&pointResp); err != nil || pointResp.Result.Payload == nil {
+		return nil, false
+	}
+
+	payload := pointResp.Result.Payload
+	chunk := &indexer.CodeChunk{
+		ID:         getString(payload, "id"),
+		FilePath:   getString(payload, "file_path"),
+		Language:   getString(payload, "language"),
+		ChunkType:  indexer.ChunkType(getString(payload, "chunk_type")),
+		Name:       getString(payload, "name"),
+		Signature:  getString(payload, "signature"),
+		StartLine:  getInt(payload, "start_line"),
+		EndLine:    getInt(payload, "end_line"),
+		DocComment: getString(payload, "doc_comment"),
+		ParentName: getString(payload, "parent_name"),
+		Content:    getString(payload, "content"),
+		References: getReferences(payload),
+	}
+
+	return &embedding.EmbeddedChunk{
+		Chunk:     chunk,
+		Embedding: pointResp.Result.Vector,
+		Version:   uint64(getInt(payload, "version")),
+	}, true
+}
+
+// CompareAndSwap upserts new only if id's current version matches
+// expectedVersion. Qdrant's HTTP points API has no native compare-and-swap,
+// so casMu serializes the read-then-write against concurrent callers in this
+// process — it can't protect against a second process hitting the same
+// collection, the same kind of documented gap as objstore_clients.go using
+// plain HTTP instead of each provider's full auth protocol.
+func (q *QdrantStore) CompareAndSwap(id string, expectedVersion uint64, new *embedding.EmbeddedChunk) error {
+	q.casMu.Lock()
+	defer q.casMu.Unlock()
+
+	var currentVersion uint64
+	if existing, ok := q.Get(id); ok {
+		currentVersion = existing.Version
+	}
+	if currentVersion != expectedVersion {
+		return ErrVersionConflict
+	}
+
+	new.Version = expectedVersion + 1
+	return q.Upsert([]*embedding.EmbeddedChunk{new})
+}
+
+// Delete removes chunks by IDs. It's a thin alias for DeleteByChunkIDs, kept
+// so QdrantStore still satisfies VectorStore's Delete method.
 func (q *QdrantStore) Delete(ids []string) error {
-	// Qdrant delete requires point IDs, not our string IDs
-	// For simplicity, we'll clear the whole collection
-	return q.Clear()
+	return q.DeleteByChunkIDs(ids)
+}
+
+// DeleteByChunkIDs removes points whose payload "id" field matches one of
+// ids, using Qdrant's filter-based delete API. This replaces the previous
+// Clear-the-whole-collection behavior, so invalidating a handful of stale
+// chunks during incremental re-indexing doesn't wipe the entire index.
+func (q *QdrantStore) DeleteByChunkIDs(ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	deleteReq := map[string]interface{}{
+		"filter": map[string]interface{}{
+			"must": []map[string]interface{}{
+				{
+					"key": "id",
+					"match": map[string]interface{}{
+						"any": ids,
+					},
+				},
+			},
+		},
+	}
+
+	body, _ := json.Marshal(deleteReq)
+	req, err := http.NewRequest("POST", fmt.Sprintf("%s/collections/%s/points/delete", q.host, q.collection), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := q.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to delete points: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("failed to delete points: %s", string(bodyBytes))
+	}
+
+	return nil
 }
 
 // Clear removes all data from the collection
@@ -254,11 +394,95 @@ func (q *QdrantStore) Count() int {
 	return collInfo.Result.PointsCount
 }
 
+// qdrantScrollPageSize is how many points All() requests per scroll call.
+const qdrantScrollPageSize = 250
+
+// All returns every chunk in the collection, paging through Qdrant's scroll
+// API (the bulk-read counterpart to Search's similarity query) until it
+// returns no next_page_offset.
+func (q *QdrantStore) All() ([]*embedding.EmbeddedChunk, error) {
+	var chunks []*embedding.EmbeddedChunk
+	var offset interface{}
+
+	for {
+		scrollReq := map[string]interface{}{
+			"limit":        qdrantScrollPageSize,
+			"with_payload": true,
+			"with_vector":  true,
+		}
+		if offset != nil {
+			scrollReq["offset"] = offset
+		}
+
+		body, _ := json.Marshal(scrollReq)
+		req, err := http.NewRequest("POST", fmt.Sprintf("%s/collections/%s/points/scroll", q.host, q.collection), bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := q.httpClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("scroll failed: %w", err)
+		}
+
+		var scrollResp struct {
+			Result struct {
+				Points []struct {
+					Vector  []float32              `json:"vector"`
+					Payload map[string]interface{} `json:"payload"`
+				} `json:"points"`
+				NextPageOffset interface{} `json:"next_page_offset"`
+			} `json:"result"`
+		}
+		decodeErr := json.NewDecoder(resp.Body).Decode(&scrollResp)
+		resp.Body.Close()
+		if decodeErr != nil {
+			return nil, fmt.Errorf("failed to decode scroll response: %w", decodeErr)
+		}
+
+		for _, p := range scrollResp.Result.Points {
+			chunk := &indexer.CodeChunk{
+				ID:         getString(p.Payload, "id"),
+				FilePath:   getString(p.Payload, "file_path"),
+				Language:   getString(p.Payload, "language"),
+				ChunkType:  indexer.ChunkType(getString(p.Payload, "chunk_type")),
+				Name:       getString(p.Payload, "name"),
+				Signature:  getString(p.Payload, "signature"),
+				StartLine:  getInt(p.Payload, "start_line"),
+				EndLine:    getInt(p.Payload, "end_line"),
+				DocComment: getString(p.Payload, "doc_comment"),
+				ParentName: getString(p.Payload, "parent_name"),
+				Content:    getString(p.Payload, "content"),
+				References: getReferences(p.Payload),
+			}
+			chunks = append(chunks, &embedding.EmbeddedChunk{
+				Chunk:     chunk,
+				Embedding: p.Vector,
+				Version:   uint64(getInt(p.Payload, "version")),
+			})
+		}
+
+		if scrollResp.Result.NextPageOffset == nil {
+			break
+		}
+		offset = scrollResp.Result.NextPageOffset
+	}
+
+	return chunks, nil
+}
+
 // Close closes the store (no-op for HTTP client)
 func (q *QdrantStore) Close() error {
 	return nil
 }
 
+// Checkpoint is a no-op: every Upsert/Delete already durably commits to
+// Qdrant's own storage before it returns, so there's nothing left to flush.
+func (q *QdrantStore) Checkpoint() error {
+	return nil
+}
+
 // HasData checks if the collection has data
 func (q *QdrantStore) HasData() bool {
 	return q.Count() > 0
@@ -286,3 +510,25 @@ func getInt(m map[string]interface{}, key string) int {
 	return 0
 }
 
+// getReferences round-trips the raw "references" payload value (decoded by
+// Qdrant's JSON response as []interface{} of map[string]interface{})
+// through JSON into []indexer.Reference, rather than hand-walking the
+// interface{} shape.
+func getReferences(m map[string]interface{}) []indexer.Reference {
+	v, ok := m["references"]
+	if !ok {
+		return nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+
+	var refs []indexer.Reference
+	if err := json.Unmarshal(data, &refs); err != nil {
+		return nil
+	}
+	return refs
+}
+
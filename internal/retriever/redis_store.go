@@ -0,0 +1,571 @@
+package retriever
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/codementor/codementor/internal/embedding"
+	"github.com/codementor/codementor/internal/indexer"
+)
+
+// redisScanCount is the COUNT hint passed to every SCAN call — how many
+// keys Redis inspects per cursor step, not a hard cap on results.
+const redisScanCount = 200
+
+// redisCosineScript runs server-side so the SCAN fallback path (see
+// searchWithScan) never has to pull every chunk's raw vector bytes across
+// the wire just to score it against the query: it's handed a batch of
+// vec:{id} keys as KEYS and the query vector's components as ARGV, and
+// returns one cosine similarity per key. Redis's embedded Lua ships the
+// "struct" library (from lua-struct), so unpacking our little-endian
+// float32 blobs doesn't need any custom bit-twiddling. Scores are returned
+// as strings rather than Lua numbers because Lua-to-RESP conversion
+// truncates numbers to integers, which would throw away everything but the
+// sign of a cosine similarity.
+const redisCosineScript = `
+local n = #ARGV
+local scores = {}
+for i, key in ipairs(KEYS) do
+    local blob = redis.call('GET', key)
+    if blob then
+        local dot, normA, normB = 0.0, 0.0, 0.0
+        for j = 1, n do
+            local q = tonumber(ARGV[j])
+            local v = struct.unpack('<f', blob, (j - 1) * 4 + 1)
+            dot = dot + v * q
+            normA = normA + v * v
+            normB = normB + q * q
+        end
+        if normA > 0 and normB > 0 then
+            scores[i] = tostring(dot / math.sqrt(normA * normB))
+        else
+            scores[i] = '0'
+        end
+    else
+        scores[i] = '0'
+    end
+end
+return scores
+`
+
+// redisCASScript implements RedisStore.CompareAndSwap atomically: check the
+// chunk hash's current "version" field against the caller's expectation,
+// and only if it matches, write every field/value pair that follows in
+// ARGV. This gives RedisStore a real single-round-trip compare-and-swap,
+// unlike QdrantStore's best-effort read-then-write (Qdrant's HTTP points
+// API has nothing to script against).
+const redisCASScript = `
+local key = KEYS[1]
+local expected = tonumber(ARGV[1])
+local current = tonumber(redis.call('HGET', key, 'version') or '0')
+if current ~= expected then
+    return -1
+end
+for i = 2, #ARGV, 2 do
+    redis.call('HSET', key, ARGV[i], ARGV[i + 1])
+end
+return 0
+`
+
+// RedisStore is a VectorStore backed by Redis, letting several API server
+// replicas behind a load balancer share one index instead of each holding
+// its own MemoryStore. Each chunk is a hash under
+// codementor:{repo}:chunk:{id} (the same metadata fields QdrantStore's
+// payload uses) with its embedding duplicated in two places: once as a
+// "vec" field inside that hash (so a RediSearch VECTOR field can index it
+// for FT.SEARCH KNN queries) and once more under a companion
+// codementor:{repo}:vec:{id} key holding just the raw little-endian float32
+// bytes, so the SCAN fallback in searchWithScan can read a vector without
+// decoding the whole hash around it.
+type RedisStore struct {
+	rdb                   *redis.Client
+	repo                  string
+	dimension             int
+	searchIndexName       string
+	searchModuleAvailable bool
+
+	// cosineScriptSHA holds the loaded redisCosineScript's SHA as an
+	// atomic.Value (always a string) rather than a bare field, since
+	// searchWithScan is meant to be called concurrently by multiple replicas
+	// and its NOSCRIPT-reload branch both reads and reassigns it.
+	cosineScriptSHA atomic.Value
+}
+
+func (s *RedisStore) loadCosineScriptSHA() string {
+	sha, _ := s.cosineScriptSHA.Load().(string)
+	return sha
+}
+
+// NewRedisStore connects to Redis at host:port (VectorConfig.Host/Port),
+// authenticating with password/db if set (VectorConfig.Password/DB), and
+// scopes every key under repo so multiple repos can share one Redis
+// instance without colliding. It best-effort creates a RediSearch vector
+// index for FT.SEARCH KNN queries; if Redis Stack's search module isn't
+// loaded, Search transparently falls back to a SCAN + Lua cosine scan.
+func NewRedisStore(host string, port int, password string, db int, repo string, dimension int) (*RedisStore, error) {
+	rdb := redis.NewClient(&redis.Options{
+		Addr:     fmt.Sprintf("%s:%d", host, port),
+		Password: password,
+		DB:       db,
+	})
+
+	ctx := context.Background()
+	if err := rdb.Ping(ctx).Err(); err != nil {
+		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+
+	s := &RedisStore{
+		rdb:             rdb,
+		repo:            repo,
+		dimension:       dimension,
+		searchIndexName: fmt.Sprintf("codementor-%s-idx", repo),
+	}
+
+	s.searchModuleAvailable = s.ensureSearchIndex(ctx) == nil
+
+	sha, err := rdb.ScriptLoad(ctx, redisCosineScript).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load cosine script: %w", err)
+	}
+	s.cosineScriptSHA.Store(sha)
+
+	return s, nil
+}
+
+// ensureSearchIndex creates repo's RediSearch vector index if it doesn't
+// already exist. It returns an error (and leaves searchModuleAvailable
+// false) when Redis Stack's search module isn't loaded, since FT.CREATE is
+// itself the cheapest probe for that.
+func (s *RedisStore) ensureSearchIndex(ctx context.Context) error {
+	if _, err := s.rdb.Do(ctx, "FT.INFO", s.searchIndexName).Result(); err == nil {
+		return nil
+	}
+
+	_, err := s.rdb.Do(ctx, "FT.CREATE", s.searchIndexName,
+		"ON", "HASH",
+		"PREFIX", "1", fmt.Sprintf("codementor:%s:chunk:", s.repo),
+		"SCHEMA",
+		"vec", "VECTOR", "HNSW", "6",
+		"TYPE", "FLOAT32",
+		"DIM", strconv.Itoa(s.dimension),
+		"DISTANCE_METRIC", "COSINE",
+	).Result()
+	return err
+}
+
+func (s *RedisStore) chunkKey(id string) string {
+	return fmt.Sprintf("codementor:%s:chunk:%s", s.repo, id)
+}
+
+func (s *RedisStore) vecKey(id string) string {
+	return fmt.Sprintf("codementor:%s:vec:%s", s.repo, id)
+}
+
+// encodeVector packs v as little-endian float32 bytes, the format both the
+// RediSearch "vec" field and the companion vec:{id} key expect.
+func encodeVector(v []float32) []byte {
+	buf := make([]byte, len(v)*4)
+	for i, f := range v {
+		binary.LittleEndian.PutUint32(buf[i*4:], math.Float32bits(f))
+	}
+	return buf
+}
+
+func decodeVector(buf []byte) []float32 {
+	v := make([]float32, len(buf)/4)
+	for i := range v {
+		v[i] = math.Float32frombits(binary.LittleEndian.Uint32(buf[i*4:]))
+	}
+	return v
+}
+
+// chunkFields builds the hash fields shared by Insert and CompareAndSwap.
+func chunkFields(chunk *embedding.EmbeddedChunk) map[string]interface{} {
+	refs, _ := json.Marshal(chunk.Chunk.References)
+	return map[string]interface{}{
+		"id":          chunk.Chunk.ID,
+		"file_path":   chunk.Chunk.FilePath,
+		"language":    chunk.Chunk.Language,
+		"chunk_type":  string(chunk.Chunk.ChunkType),
+		"name":        chunk.Chunk.Name,
+		"signature":   chunk.Chunk.Signature,
+		"start_line":  chunk.Chunk.StartLine,
+		"end_line":    chunk.Chunk.EndLine,
+		"doc_comment": chunk.Chunk.DocComment,
+		"parent_name": chunk.Chunk.ParentName,
+		"content":     chunk.Chunk.Content,
+		"references":  string(refs),
+		"version":     chunk.Version,
+		"vec":         encodeVector(chunk.Embedding),
+	}
+}
+
+// Insert adds or replaces embedded chunks, pipelined in one round trip.
+func (s *RedisStore) Insert(chunks []*embedding.EmbeddedChunk) error {
+	if len(chunks) == 0 {
+		return nil
+	}
+
+	ctx := context.Background()
+	pipe := s.rdb.Pipeline()
+	for _, chunk := range chunks {
+		vecBytes := encodeVector(chunk.Embedding)
+		pipe.HSet(ctx, s.chunkKey(chunk.Chunk.ID), chunkFields(chunk))
+		pipe.Set(ctx, s.vecKey(chunk.Chunk.ID), vecBytes, 0)
+	}
+
+	_, err := pipe.Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("redis insert failed: %w", err)
+	}
+	return nil
+}
+
+// Get returns the chunk currently stored under id, if any.
+func (s *RedisStore) Get(id string) (*embedding.EmbeddedChunk, bool) {
+	ctx := context.Background()
+	vals, err := s.rdb.HGetAll(ctx, s.chunkKey(id)).Result()
+	if err != nil || len(vals) == 0 {
+		return nil, false
+	}
+	return decodeChunkHash(vals), true
+}
+
+// decodeChunkHash turns a chunk hash's string-valued fields back into an
+// EmbeddedChunk. The "vec" field comes back as a string too (go-redis
+// decodes bulk replies as Go strings), so it's converted back to []byte
+// before being unpacked.
+func decodeChunkHash(vals map[string]string) *embedding.EmbeddedChunk {
+	var refs []indexer.Reference
+	_ = json.Unmarshal([]byte(vals["references"]), &refs)
+
+	startLine, _ := strconv.Atoi(vals["start_line"])
+	endLine, _ := strconv.Atoi(vals["end_line"])
+	version, _ := strconv.ParseUint(vals["version"], 10, 64)
+
+	chunk := &indexer.CodeChunk{
+		ID:         vals["id"],
+		FilePath:   vals["file_path"],
+		Language:   vals["language"],
+		ChunkType:  indexer.ChunkType(vals["chunk_type"]),
+		Name:       vals["name"],
+		Signature:  vals["signature"],
+		StartLine:  startLine,
+		EndLine:    endLine,
+		DocComment: vals["doc_comment"],
+		ParentName: vals["parent_name"],
+		Content:    vals["content"],
+		References: refs,
+	}
+
+	return &embedding.EmbeddedChunk{
+		Chunk:     chunk,
+		Embedding: decodeVector([]byte(vals["vec"])),
+		Version:   version,
+	}
+}
+
+// CompareAndSwap stores new only if id's current version matches
+// expectedVersion, via redisCASScript — a single EVAL round trip rather
+// than QdrantStore's read-then-write, since Redis lets us script the check
+// and the write together.
+func (s *RedisStore) CompareAndSwap(id string, expectedVersion uint64, new *embedding.EmbeddedChunk) error {
+	ctx := context.Background()
+	new.Version = expectedVersion + 1
+
+	fields := chunkFields(new)
+	argv := make([]interface{}, 0, 1+len(fields)*2)
+	argv = append(argv, expectedVersion)
+	for k, v := range fields {
+		argv = append(argv, k, v)
+	}
+
+	result, err := s.rdb.Eval(ctx, redisCASScript, []string{s.chunkKey(id)}, argv...).Result()
+	if err != nil {
+		return fmt.Errorf("redis CAS failed: %w", err)
+	}
+	if n, ok := result.(int64); ok && n == -1 {
+		return ErrVersionConflict
+	}
+
+	// The CAS script only touched the chunk hash; keep the companion
+	// vec:{id} key the SCAN fallback reads in sync too.
+	if err := s.rdb.Set(ctx, s.vecKey(id), encodeVector(new.Embedding), 0).Err(); err != nil {
+		return fmt.Errorf("failed to update vector key: %w", err)
+	}
+	return nil
+}
+
+// Search finds similar chunks, preferring RediSearch's FT.SEARCH ... KNN
+// when the vector index is available and falling back to a SCAN + Lua
+// cosine scan (searchWithScan) otherwise, so a Redis instance without the
+// search module still works, just without sub-linear search.
+func (s *RedisStore) Search(queryEmbedding []float32, topK int) ([]*SearchResult, error) {
+	ctx := context.Background()
+
+	if s.searchModuleAvailable {
+		results, err := s.searchWithModule(ctx, queryEmbedding, topK)
+		if err == nil {
+			return results, nil
+		}
+		// The module call failed at runtime (e.g. FT.DROPINDEX ran, or the
+		// module was unloaded after NewRedisStore's probe) — fall through
+		// instead of failing the whole search.
+	}
+
+	return s.searchWithScan(ctx, queryEmbedding, topK)
+}
+
+func (s *RedisStore) searchWithModule(ctx context.Context, query []float32, topK int) ([]*SearchResult, error) {
+	blob := encodeVector(query)
+
+	raw, err := s.rdb.Do(ctx, "FT.SEARCH", s.searchIndexName,
+		fmt.Sprintf("*=>[KNN %d @vec $BLOB AS score]", topK),
+		"PARAMS", "2", "BLOB", blob,
+		"SORTBY", "score",
+		"DIALECT", "2",
+	).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	return parseFTSearchReply(raw), nil
+}
+
+// parseFTSearchReply walks FT.SEARCH's reply shape — a leading total-match
+// count followed by (key, fields) pairs, fields itself a flat
+// [field, value, field, value, ...] list — into SearchResults.
+func parseFTSearchReply(raw interface{}) []*SearchResult {
+	rows, ok := raw.([]interface{})
+	if !ok || len(rows) < 2 {
+		return nil
+	}
+
+	var results []*SearchResult
+	for i := 1; i+1 < len(rows); i += 2 {
+		fields, ok := rows[i+1].([]interface{})
+		if !ok {
+			continue
+		}
+
+		vals := make(map[string]string, len(fields)/2)
+		for j := 0; j+1 < len(fields); j += 2 {
+			vals[fmt.Sprintf("%v", fields[j])] = fmt.Sprintf("%v", fields[j+1])
+		}
+
+		// The KNN clause's distance comes back under the "score" alias we
+		// asked for; RediSearch's COSINE metric reports distance, so
+		// similarity is 1 - distance, same convention MemoryStore uses.
+		distance, _ := strconv.ParseFloat(vals["score"], 32)
+		embeddedChunk := decodeChunkHash(vals)
+
+		results = append(results, &SearchResult{
+			Chunk:    embeddedChunk.Chunk,
+			Score:    1 - float32(distance),
+			Distance: float32(distance),
+		})
+	}
+	return results
+}
+
+// searchWithScan is the fallback path: walk every codementor:{repo}:vec:*
+// key with SCAN (so a 100k-chunk repo is never pulled into this process in
+// one shot), scoring each batch server-side with redisCosineScript instead
+// of transferring the raw vectors to score them here.
+func (s *RedisStore) searchWithScan(ctx context.Context, query []float32, topK int) ([]*SearchResult, error) {
+	pattern := fmt.Sprintf("codementor:%s:vec:*", s.repo)
+	prefix := fmt.Sprintf("codementor:%s:vec:", s.repo)
+
+	argv := make([]interface{}, len(query))
+	for i, f := range query {
+		argv[i] = strconv.FormatFloat(float64(f), 'f', -1, 32)
+	}
+
+	type scored struct {
+		id    string
+		score float32
+	}
+	var all []scored
+
+	var cursor uint64
+	for {
+		keys, next, err := s.rdb.Scan(ctx, cursor, pattern, redisScanCount).Result()
+		if err != nil {
+			return nil, fmt.Errorf("redis scan failed: %w", err)
+		}
+
+		if len(keys) > 0 {
+			reply, err := s.rdb.EvalSha(ctx, s.loadCosineScriptSHA(), keys, argv...).Result()
+			if err != nil {
+				// The script cache can be evicted independently of this
+				// connection (SCRIPT FLUSH, or a failover to a replica that
+				// never loaded it) — reload once and retry this batch.
+				sha, loadErr := s.rdb.ScriptLoad(ctx, redisCosineScript).Result()
+				if loadErr != nil {
+					return nil, fmt.Errorf("redis cosine script failed: %w", err)
+				}
+				s.cosineScriptSHA.Store(sha)
+				reply, err = s.rdb.EvalSha(ctx, sha, keys, argv...).Result()
+				if err != nil {
+					return nil, fmt.Errorf("redis cosine script failed: %w", err)
+				}
+			}
+
+			scores, ok := reply.([]interface{})
+			if !ok || len(scores) != len(keys) {
+				return nil, fmt.Errorf("unexpected cosine script reply")
+			}
+			for i, key := range keys {
+				id := strings.TrimPrefix(key, prefix)
+				score, _ := strconv.ParseFloat(fmt.Sprintf("%v", scores[i]), 32)
+				all = append(all, scored{id: id, score: float32(score)})
+			}
+		}
+
+		cursor = next
+		if cursor == 0 {
+			break
+		}
+	}
+
+	sort.Slice(all, func(i, j int) bool { return all[i].score > all[j].score })
+	if topK > len(all) {
+		topK = len(all)
+	}
+
+	results := make([]*SearchResult, 0, topK)
+	for _, sc := range all[:topK] {
+		embeddedChunk, ok := s.Get(sc.id)
+		if !ok {
+			continue
+		}
+		results = append(results, &SearchResult{
+			Chunk:    embeddedChunk.Chunk,
+			Score:    sc.score,
+			Distance: 1 - sc.score,
+		})
+	}
+
+	return results, nil
+}
+
+// Delete removes chunks by IDs
+func (s *RedisStore) Delete(ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	ctx := context.Background()
+	keys := make([]string, 0, len(ids)*2)
+	for _, id := range ids {
+		keys = append(keys, s.chunkKey(id), s.vecKey(id))
+	}
+
+	if err := s.rdb.Del(ctx, keys...).Err(); err != nil {
+		return fmt.Errorf("redis delete failed: %w", err)
+	}
+	return nil
+}
+
+// Clear removes every key under this repo's prefix.
+func (s *RedisStore) Clear() error {
+	ctx := context.Background()
+	pattern := fmt.Sprintf("codementor:%s:*", s.repo)
+
+	var cursor uint64
+	for {
+		keys, next, err := s.rdb.Scan(ctx, cursor, pattern, redisScanCount).Result()
+		if err != nil {
+			return fmt.Errorf("redis scan failed: %w", err)
+		}
+		if len(keys) > 0 {
+			if err := s.rdb.Del(ctx, keys...).Err(); err != nil {
+				return fmt.Errorf("redis delete failed: %w", err)
+			}
+		}
+		cursor = next
+		if cursor == 0 {
+			break
+		}
+	}
+
+	return nil
+}
+
+// Count returns the number of stored chunks
+func (s *RedisStore) Count() int {
+	ctx := context.Background()
+	pattern := fmt.Sprintf("codementor:%s:chunk:*", s.repo)
+
+	var count int
+	var cursor uint64
+	for {
+		keys, next, err := s.rdb.Scan(ctx, cursor, pattern, redisScanCount).Result()
+		if err != nil {
+			return count
+		}
+		count += len(keys)
+		cursor = next
+		if cursor == 0 {
+			break
+		}
+	}
+	return count
+}
+
+// All returns every chunk for this repo, scanning codementor:{repo}:chunk:*
+// and reading each hash back, same decoding Get uses.
+func (s *RedisStore) All() ([]*embedding.EmbeddedChunk, error) {
+	ctx := context.Background()
+	pattern := fmt.Sprintf("codementor:%s:chunk:*", s.repo)
+
+	var chunks []*embedding.EmbeddedChunk
+	var cursor uint64
+	for {
+		keys, next, err := s.rdb.Scan(ctx, cursor, pattern, redisScanCount).Result()
+		if err != nil {
+			return nil, fmt.Errorf("redis scan failed: %w", err)
+		}
+
+		for _, key := range keys {
+			vals, err := s.rdb.HGetAll(ctx, key).Result()
+			if err != nil || len(vals) == 0 {
+				continue
+			}
+			chunks = append(chunks, decodeChunkHash(vals))
+		}
+
+		cursor = next
+		if cursor == 0 {
+			break
+		}
+	}
+
+	return chunks, nil
+}
+
+// Close closes the underlying Redis connection pool.
+func (s *RedisStore) Close() error {
+	return s.rdb.Close()
+}
+
+// Checkpoint is a no-op: every Insert/CompareAndSwap/Delete already commits
+// to Redis (and whatever AOF/RDB persistence it's configured with) before
+// returning, so there's nothing left for RedisStore itself to flush.
+func (s *RedisStore) Checkpoint() error {
+	return nil
+}
+
+// HasData checks if the store has any chunks for this repo.
+func (s *RedisStore) HasData() bool {
+	return s.Count() > 0
+}
@@ -0,0 +1,253 @@
+package retriever
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/codementor/codementor/internal/embedcache"
+	"github.com/codementor/codementor/internal/llm"
+)
+
+// Reranker rescores a candidate list against query, so a caller can trade
+// the extra latency/cost of a second pass for better precision at the top
+// of the list than Reciprocal Rank Fusion's rank-only scoring gives alone.
+// Results are returned sorted best-first; candidates isn't mutated.
+type Reranker interface {
+	Rerank(ctx context.Context, query string, candidates []*SearchResult) ([]*SearchResult, error)
+}
+
+// rerankCacheSize bounds LLMReranker's cache to this many (query, chunk)
+// scores, evicting the least recently used entry once full — repeated
+// queries against a mostly-unchanged index are the common case this speeds
+// up, and an unbounded cache would otherwise grow with every distinct
+// query/chunk pair ever seen.
+const rerankCacheSize = 2048
+
+// LLMReranker is the default Reranker: it asks client.Chat to score each
+// (query, chunk) pair with a compact prompt, caching scores by
+// sha256(query+chunk.ID) so a repeated query against the same chunk doesn't
+// re-invoke the model.
+type LLMReranker struct {
+	client llm.Provider
+	cache  *rerankCache
+}
+
+// NewLLMReranker creates an LLMReranker backed by client (the same
+// llm.Provider HybridRetriever already uses for embeddings — see
+// NewHybridRetriever).
+func NewLLMReranker(client llm.Provider) *LLMReranker {
+	return &LLMReranker{
+		client: client,
+		cache:  newRerankCache(rerankCacheSize),
+	}
+}
+
+// rerankConcurrency bounds how many Chat scoring calls Rerank has in flight
+// at once — each candidate's score is an independent round-trip, so scoring
+// them one at a time would multiply SearchWithRerank's latency by the
+// candidate count for no benefit. Mirrors the sem := make(chan struct{},
+// concurrency) pattern Embedder.EmbedChunks already uses for the same
+// reason.
+const rerankConcurrency = 4
+
+// scoredResult pairs a reranked candidate with whether the model actually
+// scored it, so a scoring failure can be sorted after every successfully
+// scored candidate instead of competing on a score it never earned — an
+// LLM rerank score and an RRF fusion score aren't on the same scale, so
+// comparing them directly would badly mis-rank whichever chunks failed to
+// score.
+type scoredResult struct {
+	result *SearchResult
+	scored bool
+}
+
+// Rerank scores each candidate with client.Chat and returns them sorted by
+// score, descending, with any candidate the model failed to score (a
+// malformed response, a Chat error) placed after every successfully scored
+// one, in its original RRF order, instead of being dropped or competing
+// directly against LLM scores on an incomparable scale. Scoring happens
+// with up to rerankConcurrency calls in flight at once.
+func (r *LLMReranker) Rerank(ctx context.Context, query string, candidates []*SearchResult) ([]*SearchResult, error) {
+	scored := make([]scoredResult, len(candidates))
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, rerankConcurrency)
+
+	for i, c := range candidates {
+		wg.Add(1)
+		go func(i int, c *SearchResult) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			score, err := r.score(ctx, query, c)
+			result := *c
+			scored[i] = scoredResult{result: &result, scored: err == nil}
+			if err == nil {
+				result.Score = score
+			}
+		}(i, c)
+	}
+	wg.Wait()
+
+	sort.SliceStable(scored, func(i, j int) bool {
+		if scored[i].scored != scored[j].scored {
+			return scored[i].scored // scored candidates sort before unscored ones
+		}
+		return scored[i].result.Score > scored[j].result.Score
+	})
+
+	reranked := make([]*SearchResult, len(scored))
+	for i, s := range scored {
+		reranked[i] = s.result
+	}
+
+	return reranked, nil
+}
+
+// score returns a (query, chunk) pair's rerank score, consulting the cache
+// before asking the model.
+func (r *LLMReranker) score(ctx context.Context, query string, result *SearchResult) (float32, error) {
+	key := rerankCacheKey(query, result.Chunk.ID)
+	if score, ok := r.cache.get(key); ok {
+		return score, nil
+	}
+
+	response, err := r.client.Chat(ctx, []llm.Message{
+		{Role: "system", Content: "You score how relevant a code chunk is to a search query. Respond with ONLY a JSON object of the form {\"score\": 0.0} where score is between 0 and 1."},
+		{Role: "user", Content: rerankPrompt(query, result.Chunk.Content)},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to score chunk %s: %w", result.Chunk.ID, err)
+	}
+
+	score, err := parseRerankScore(response)
+	if err != nil {
+		return 0, err
+	}
+
+	r.cache.put(key, score)
+	return score, nil
+}
+
+// rerankPrompt builds the scoring prompt for one (query, chunk) pair,
+// truncating chunk content so a long chunk doesn't blow out the context
+// window of a rerank call issued once per candidate.
+func rerankPrompt(query, content string) string {
+	const maxChunkRunes = 2000
+	if runes := []rune(content); len(runes) > maxChunkRunes {
+		content = string(runes[:maxChunkRunes])
+	}
+	return fmt.Sprintf("Query: %s\n\nCode chunk:\n%s", query, content)
+}
+
+// rerankScoreResponse is the JSON shape LLMReranker's scoring prompt asks
+// the model to reply with. Score is a pointer so parseRerankScore can tell
+// "score omitted" (a malformed reply) apart from an explicit 0.0.
+type rerankScoreResponse struct {
+	Score *float32 `json:"score"`
+}
+
+// parseRerankScore extracts the score from a model's reply, tolerating
+// leading/trailing prose around the JSON object (models asked for
+// "ONLY JSON" still sometimes wrap it in a sentence or code fence). A reply
+// that parses as JSON but omits "score" is treated as an error rather than
+// an implicit 0.0, so it falls into Rerank's unscored tail instead of
+// sinking to the bottom as if the model had confidently scored it zero.
+func parseRerankScore(response string) (float32, error) {
+	start := strings.Index(response, "{")
+	end := strings.LastIndex(response, "}")
+	if start < 0 || end < start {
+		return 0, fmt.Errorf("no JSON object in rerank response: %q", response)
+	}
+
+	var parsed rerankScoreResponse
+	if err := json.Unmarshal([]byte(response[start:end+1]), &parsed); err != nil {
+		return 0, fmt.Errorf("failed to parse rerank response: %w", err)
+	}
+	if parsed.Score == nil {
+		return 0, fmt.Errorf("rerank response has no \"score\" field: %q", response)
+	}
+
+	switch {
+	case *parsed.Score < 0:
+		return 0, nil
+	case *parsed.Score > 1:
+		return 1, nil
+	default:
+		return *parsed.Score, nil
+	}
+}
+
+// rerankCacheKey builds a cache key from query and chunkID, reusing
+// embedcache.Key's sha256(a+":"+b) construction rather than a second copy of
+// it.
+func rerankCacheKey(query, chunkID string) string {
+	return embedcache.Key(query, chunkID)
+}
+
+// rerankCache is a fixed-capacity, least-recently-used cache of rerank
+// scores. container/list backs the recency ordering; entries maps a key to
+// its list element so get/put are both O(1).
+type rerankCache struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List
+	entries  map[string]*list.Element
+}
+
+// rerankCacheEntry is the value stored in rerankCache.order's elements.
+type rerankCacheEntry struct {
+	key   string
+	score float32
+}
+
+func newRerankCache(capacity int) *rerankCache {
+	return &rerankCache{
+		capacity: capacity,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+// get returns key's cached score, if present, marking it most recently used.
+func (c *rerankCache) get(key string) (float32, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return 0, false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*rerankCacheEntry).score, true
+}
+
+// put stores score under key, evicting the least recently used entry if the
+// cache is at capacity.
+func (c *rerankCache) put(key string, score float32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.entries[key]; ok {
+		el.Value.(*rerankCacheEntry).score = score
+		c.order.MoveToFront(el)
+		return
+	}
+
+	el := c.order.PushFront(&rerankCacheEntry{key: key, score: score})
+	c.entries[key] = el
+
+	if c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		if oldest != nil {
+			c.order.Remove(oldest)
+			delete(c.entries, oldest.Value.(*rerankCacheEntry).key)
+		}
+	}
+}
@@ -0,0 +1,422 @@
+package retriever
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/codementor/codementor/internal/indexer"
+)
+
+// Segment file layout (little-endian):
+//
+//	uint32 magic
+//	uint32 version
+//	uint32 docCount
+//	uint64 docSectionLen
+//	[docSectionLen]byte   docSection    -- docCount x (uvarint len, JSON chunk)
+//	docCount x uvarint    docLengths    -- token count per doc, for BM25 scoring
+//	uint64 termDictLen
+//	[termDictLen]byte     termDict      -- uint32 count, then per term:
+//	                                       uvarint termLen, term bytes,
+//	                                       uvarint docFreq, uvarint postingOff,
+//	                                       uvarint postingLen
+//	...                   postings      -- per term: docFreq x (uvarint
+//	                                       deltaDocID, uvarint termFreq)
+//
+// Everything after the header is mmap'd and decoded lazily: doc JSON is only
+// unmarshaled for docs that make it into a result set, and a term's posting
+// list is only sliced out of the mapped bytes when that term appears in a
+// query.
+const (
+	segmentMagic   uint32 = 0x53454731 // "SEG1"
+	segmentVersion uint32 = 1
+)
+
+// termEntry is one row of a segment's in-memory term dictionary: just
+// enough (term, docFreq, and where its postings live) to binary-search by
+// term and then slice the mmap'd postings out on demand. This stands in for
+// an FST, which would be more compact but isn't worth vendoring a new
+// dependency for in a tree with no dependency management.
+type termEntry struct {
+	term       string
+	docFreq    int
+	postingOff int64
+	postingLen int64
+}
+
+// segment is one immutable, on-disk chunk of the BM25 index. Index and
+// Update each write one; Merge later combines small segments into bigger
+// ones. Nothing about a segment changes after it's written except its
+// tombstone bitmap, which marks deleted doc IDs without touching the
+// immutable postings/doc data.
+type segment struct {
+	id       string
+	path     string
+	docCount int
+
+	docLengths []int
+	terms      []termEntry // sorted by term
+
+	data          mmapData
+	docSectionOff int64
+	docOffsets    []int64 // docCount+1 cumulative byte offsets within the doc section
+	postingsOff   int64   // absolute offset where the postings blob starts
+
+	tombstones    map[uint32]bool
+	tombstonePath string
+}
+
+// buildSegment tokenizes chunks, writes an immutable segment file under dir,
+// and opens it (mmapping it back in) as the returned segment.
+func buildSegment(dir, id string, chunks []*indexer.CodeChunk, tokenize func(string) []string) (*segment, error) {
+	type posting struct {
+		docID uint32
+		freq  uint32
+	}
+
+	docCount := len(chunks)
+	docLengths := make([]int, docCount)
+	postings := make(map[string][]posting)
+
+	var docSection bytes.Buffer
+	for i, c := range chunks {
+		docJSON, err := json.Marshal(c)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode chunk %s: %w", c.ID, err)
+		}
+		writeUvarint(&docSection, uint64(len(docJSON)))
+		docSection.Write(docJSON)
+
+		tokens := tokenize(c.Content)
+		docLengths[i] = len(tokens)
+
+		counts := make(map[string]int, len(tokens))
+		for _, t := range tokens {
+			counts[t]++
+		}
+		for term, freq := range counts {
+			postings[term] = append(postings[term], posting{docID: uint32(i), freq: uint32(freq)})
+		}
+	}
+
+	terms := make([]string, 0, len(postings))
+	for t := range postings {
+		terms = append(terms, t)
+	}
+	sort.Strings(terms)
+
+	var postingsSection bytes.Buffer
+	entries := make([]termEntry, len(terms))
+	for i, term := range terms {
+		list := postings[term]
+		off := int64(postingsSection.Len())
+
+		var prevDoc uint32
+		for _, p := range list {
+			writeUvarint(&postingsSection, uint64(p.docID-prevDoc))
+			writeUvarint(&postingsSection, uint64(p.freq))
+			prevDoc = p.docID
+		}
+
+		entries[i] = termEntry{
+			term:       term,
+			docFreq:    len(list),
+			postingOff: off,
+			postingLen: int64(postingsSection.Len()) - off,
+		}
+	}
+
+	var termDict bytes.Buffer
+	writeUint32(&termDict, uint32(len(entries)))
+	for _, e := range entries {
+		writeUvarint(&termDict, uint64(len(e.term)))
+		termDict.WriteString(e.term)
+		writeUvarint(&termDict, uint64(e.docFreq))
+		writeUvarint(&termDict, uint64(e.postingOff))
+		writeUvarint(&termDict, uint64(e.postingLen))
+	}
+
+	var out bytes.Buffer
+	writeUint32(&out, segmentMagic)
+	writeUint32(&out, segmentVersion)
+	writeUint32(&out, uint32(docCount))
+	writeUint64(&out, uint64(docSection.Len()))
+	out.Write(docSection.Bytes())
+	for _, l := range docLengths {
+		writeUvarint(&out, uint64(l))
+	}
+	writeUint64(&out, uint64(termDict.Len()))
+	out.Write(termDict.Bytes())
+	out.Write(postingsSection.Bytes())
+
+	path := filepath.Join(dir, id+".seg")
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, out.Bytes(), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write segment: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return nil, fmt.Errorf("failed to finalize segment: %w", err)
+	}
+
+	return openSegment(path, id)
+}
+
+// openSegment mmaps an existing segment file and parses its doc lengths and
+// term dictionary into memory, leaving doc content and postings to be
+// decoded lazily from the mapped bytes.
+func openSegment(path, id string) (*segment, error) {
+	mapped, err := mmapFile(path)
+	if err != nil {
+		return nil, err
+	}
+	data := mapped.Bytes()
+
+	pos := 0
+	readU32 := func() uint32 {
+		v := binary.LittleEndian.Uint32(data[pos:])
+		pos += 4
+		return v
+	}
+	readU64 := func() uint64 {
+		v := binary.LittleEndian.Uint64(data[pos:])
+		pos += 8
+		return v
+	}
+	readUvarint := func() uint64 {
+		v, n := binary.Uvarint(data[pos:])
+		pos += n
+		return v
+	}
+
+	magic := readU32()
+	if magic != segmentMagic {
+		mapped.Close()
+		return nil, fmt.Errorf("corrupt segment %s: bad magic", path)
+	}
+	_ = readU32() // version, only one so far
+	docCount := int(readU32())
+	docSectionLen := int64(readU64())
+
+	docSectionOff := int64(pos)
+	docOffsets := make([]int64, docCount+1)
+	scanPos := pos
+	for i := 0; i < docCount; i++ {
+		docOffsets[i] = int64(scanPos - pos)
+		length, n := binary.Uvarint(data[scanPos:])
+		scanPos += n + int(length)
+	}
+	docOffsets[docCount] = int64(scanPos - pos)
+	pos = int(docSectionOff) + int(docSectionLen)
+
+	docLengths := make([]int, docCount)
+	for i := range docLengths {
+		docLengths[i] = int(readUvarint())
+	}
+
+	termDictLen := int64(readU64())
+	termDictEnd := pos + int(termDictLen)
+
+	termCount := int(readU32())
+	terms := make([]termEntry, termCount)
+	for i := 0; i < termCount; i++ {
+		termLen := readUvarint()
+		term := string(data[pos : pos+int(termLen)])
+		pos += int(termLen)
+		docFreq := readUvarint()
+		postingOff := readUvarint()
+		postingLen := readUvarint()
+		terms[i] = termEntry{term: term, docFreq: int(docFreq), postingOff: int64(postingOff), postingLen: int64(postingLen)}
+	}
+	pos = termDictEnd
+
+	seg := &segment{
+		id:            id,
+		path:          path,
+		docCount:      docCount,
+		docLengths:    docLengths,
+		terms:         terms,
+		data:          mapped,
+		docSectionOff: docSectionOff,
+		docOffsets:    docOffsets,
+		postingsOff:   int64(pos),
+		tombstonePath: path + ".tombstones",
+	}
+
+	tombstones, err := loadTombstones(seg.tombstonePath)
+	if err != nil {
+		mapped.Close()
+		return nil, err
+	}
+	seg.tombstones = tombstones
+
+	return seg, nil
+}
+
+// lookupTerm binary-searches the term dictionary and decodes that term's
+// posting list (docID, termFreq pairs, local to this segment) out of the
+// mmap'd postings section. It returns nil if the term isn't present.
+func (s *segment) lookupTerm(term string) []struct {
+	docID uint32
+	freq  uint32
+} {
+	i := sort.Search(len(s.terms), func(i int) bool { return s.terms[i].term >= term })
+	if i >= len(s.terms) || s.terms[i].term != term {
+		return nil
+	}
+
+	e := s.terms[i]
+	start := s.postingsOff + e.postingOff
+	data := s.data.Bytes()[start : start+e.postingLen]
+
+	result := make([]struct {
+		docID uint32
+		freq  uint32
+	}, 0, e.docFreq)
+
+	var docID uint32
+	pos := 0
+	for len(result) < e.docFreq {
+		delta, n := binary.Uvarint(data[pos:])
+		pos += n
+		freq, n := binary.Uvarint(data[pos:])
+		pos += n
+		docID += uint32(delta)
+		result = append(result, struct {
+			docID uint32
+			freq  uint32
+		}{docID: docID, freq: uint32(freq)})
+	}
+
+	return result
+}
+
+// docFreq returns the number of (live or not) docs containing term.
+func (s *segment) docFreq(term string) int {
+	i := sort.Search(len(s.terms), func(i int) bool { return s.terms[i].term >= term })
+	if i >= len(s.terms) || s.terms[i].term != term {
+		return 0
+	}
+	return s.terms[i].docFreq
+}
+
+// decodeDoc unmarshals the full chunk for a local doc ID out of the mmap'd
+// doc section. This is the only place a segment's doc content is decoded,
+// so callers should only do it for docs that survive scoring and ranking.
+func (s *segment) decodeDoc(docID uint32) (*indexer.CodeChunk, error) {
+	start := s.docSectionOff + s.docOffsets[docID]
+	data := s.data.Bytes()[start:]
+
+	length, n := binary.Uvarint(data)
+	docJSON := data[n : n+int(length)]
+
+	var chunk indexer.CodeChunk
+	if err := json.Unmarshal(docJSON, &chunk); err != nil {
+		return nil, fmt.Errorf("failed to decode doc %d in segment %s: %w", docID, s.id, err)
+	}
+	return &chunk, nil
+}
+
+// isLive reports whether docID hasn't been tombstoned.
+func (s *segment) isLive(docID uint32) bool {
+	return !s.tombstones[docID]
+}
+
+// liveDocCount returns how many docs in this segment aren't tombstoned.
+func (s *segment) liveDocCount() int {
+	return s.docCount - len(s.tombstones)
+}
+
+// tombstone marks docID as deleted and persists the tombstone set so a
+// restart doesn't resurrect it. Tombstoning is O(1) at write time; the
+// segment's immutable postings/doc data is left untouched until Merge
+// physically drops the doc.
+func (s *segment) tombstone(docID uint32) error {
+	if s.tombstones[docID] {
+		return nil
+	}
+	s.tombstones[docID] = true
+	return saveTombstones(s.tombstonePath, s.tombstones)
+}
+
+// close unmaps the segment's file.
+func (s *segment) close() error {
+	return s.data.Close()
+}
+
+// delete removes the segment's file and tombstone sidecar from disk, used
+// after Merge folds it into a bigger segment.
+func (s *segment) delete() error {
+	if err := s.close(); err != nil {
+		return err
+	}
+	if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Remove(s.tombstonePath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func loadTombstones(path string) (map[uint32]bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return make(map[uint32]bool), nil
+		}
+		return nil, fmt.Errorf("failed to read tombstones: %w", err)
+	}
+
+	var ids []uint32
+	if err := json.Unmarshal(data, &ids); err != nil {
+		return nil, fmt.Errorf("failed to parse tombstones: %w", err)
+	}
+
+	set := make(map[uint32]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	return set, nil
+}
+
+func saveTombstones(path string, set map[uint32]bool) error {
+	ids := make([]uint32, 0, len(set))
+	for id := range set {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	data, err := json.Marshal(ids)
+	if err != nil {
+		return fmt.Errorf("failed to encode tombstones: %w", err)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write tombstones: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+func writeUint32(buf *bytes.Buffer, v uint32) {
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], v)
+	buf.Write(b[:])
+}
+
+func writeUint64(buf *bytes.Buffer, v uint64) {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], v)
+	buf.Write(b[:])
+}
+
+func writeUvarint(buf *bytes.Buffer, v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(b[:], v)
+	buf.Write(b[:n])
+}
@@ -1,10 +1,19 @@
 package retriever
 
 import (
+	"errors"
+
 	"github.com/codementor/codementor/internal/embedding"
 	"github.com/codementor/codementor/internal/indexer"
 )
 
+// ErrVersionConflict is returned by VectorStore.CompareAndSwap when the
+// store's current version for a chunk ID doesn't match the caller's
+// expectedVersion, meaning another writer updated it first. Callers retry
+// by re-reading with Get and recomputing their update against the new
+// version, mirroring etcd's GuaranteedUpdate compare-and-swap loop.
+var ErrVersionConflict = errors.New("retriever: version conflict")
+
 // SearchResult represents a search result with similarity score
 type SearchResult struct {
 	Chunk    *indexer.CodeChunk `json:"chunk"`
@@ -31,5 +40,31 @@ type VectorStore interface {
 
 	// Close closes the store connection
 	Close() error
+
+	// Get returns the chunk currently stored under id, and whether one
+	// exists, so a TryUpdate-style caller can inspect its Version/ContentHash
+	// before attempting a CompareAndSwap.
+	Get(id string) (*embedding.EmbeddedChunk, bool)
+
+	// CompareAndSwap stores new under its own chunk ID only if the store's
+	// current version for that ID equals expectedVersion (expectedVersion
+	// == 0 means "doesn't exist yet"). On success new.Version is set to
+	// expectedVersion+1. On a mismatch it returns ErrVersionConflict and
+	// leaves the store unchanged, so the caller can re-Get and retry.
+	CompareAndSwap(id string, expectedVersion uint64, new *embedding.EmbeddedChunk) error
+
+	// Checkpoint durably persists the store's current state, if the backend
+	// has anything to flush (e.g. MemoryStore's WAL, or the chunks ObjStore
+	// still buffers short of a full segment). Backends that are already
+	// durable on every write (QdrantStore, BoltStore, RedisStore) make this a
+	// no-op.
+	Checkpoint() error
+
+	// All returns every chunk currently in the store. It exists for
+	// operations that need the whole corpus rather than a similarity search
+	// against it (e.g. embedding.Embedder.MigrateStore re-embedding
+	// everything after a model swap), so it's allowed to be slow relative to
+	// Search.
+	All() ([]*embedding.EmbeddedChunk, error)
 }
 
@@ -0,0 +1,380 @@
+package retriever
+
+import (
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+
+	"github.com/codementor/codementor/internal/indexer"
+)
+
+// TrigramIndex maps every 3-byte substring of a chunk's content to the
+// chunks that contain it, so arbitrary substrings and regexes can be
+// searched in roughly the time it takes to intersect a handful of posting
+// lists instead of scanning every chunk. This mirrors the classic Google
+// Code Search / Zoekt approach to sub-second regex search over a corpus.
+type TrigramIndex struct {
+	chunks   []*indexer.CodeChunk
+	postings map[string][]uint32 // trigram -> sorted chunk indices
+}
+
+// NewTrigramIndex creates an empty TrigramIndex.
+func NewTrigramIndex() *TrigramIndex {
+	return &TrigramIndex{postings: make(map[string][]uint32)}
+}
+
+// Index builds the trigram index from scratch for chunks.
+func (t *TrigramIndex) Index(chunks []*indexer.CodeChunk) {
+	t.chunks = chunks
+	t.postings = make(map[string][]uint32)
+
+	for i, c := range chunks {
+		for _, tri := range uniqueTrigrams(c.Content) {
+			// chunks are visited in increasing i order, so each posting
+			// list comes out sorted for free.
+			t.postings[tri] = append(t.postings[tri], uint32(i))
+		}
+	}
+}
+
+// Update rebuilds the index over the current chunk set with removed chunks
+// dropped and added chunks appended. Unlike BM25.Update, this isn't an
+// incremental patch of the posting lists (removing a chunk would otherwise
+// require renumbering every other posting), but rebuilding is still just a
+// single pass over chunk content, which is cheap relative to embedding.
+func (t *TrigramIndex) Update(added, removed []*indexer.CodeChunk) {
+	if len(added) == 0 && len(removed) == 0 {
+		return
+	}
+
+	removeSet := make(map[string]bool, len(removed))
+	for _, c := range removed {
+		removeSet[c.ID] = true
+	}
+
+	chunks := make([]*indexer.CodeChunk, 0, len(t.chunks)+len(added))
+	for _, c := range t.chunks {
+		if !removeSet[c.ID] {
+			chunks = append(chunks, c)
+		}
+	}
+	chunks = append(chunks, added...)
+
+	t.Index(chunks)
+}
+
+// Search matches pattern against every chunk's content. A pattern with no
+// regex metacharacters is treated as a literal substring and verified with
+// strings.Contains; otherwise it's compiled as a regexp and its mandatory
+// trigram set is extracted from the parsed syntax tree to narrow the
+// candidates before running the full regexp match.
+func (t *TrigramIndex) Search(pattern string) ([]*SearchResult, error) {
+	if isLiteralPattern(pattern) {
+		return t.searchLiteral(pattern), nil
+	}
+	return t.searchRegexp(pattern)
+}
+
+func (t *TrigramIndex) searchLiteral(pattern string) []*SearchResult {
+	trigrams := uniqueTrigrams(pattern)
+	var candidates []uint32
+	if len(trigrams) == 0 {
+		// Pattern shorter than 3 bytes can't be trigram-filtered.
+		candidates = allIndices(len(t.chunks))
+	} else {
+		candidates = t.intersectTrigrams(trigrams)
+	}
+
+	return t.verify(candidates, func(content string) bool {
+		return strings.Contains(content, pattern)
+	})
+}
+
+func (t *TrigramIndex) searchRegexp(pattern string) ([]*SearchResult, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern: %w", err)
+	}
+
+	parsed, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern: %w", err)
+	}
+
+	query := buildTrigramQuery(parsed.Simplify())
+	postings, constrained := t.eval(query)
+
+	candidates := postings
+	if !constrained {
+		candidates = allIndices(len(t.chunks))
+	}
+
+	return t.verify(candidates, re.MatchString), nil
+}
+
+// intersectTrigrams returns the sorted, deduplicated intersection of the
+// posting lists for every trigram in trigrams. A trigram with no posting
+// list means no chunk can match, so the whole intersection is empty.
+func (t *TrigramIndex) intersectTrigrams(trigrams []string) []uint32 {
+	var result []uint32
+	for i, tri := range trigrams {
+		posting, ok := t.postings[tri]
+		if !ok {
+			return nil
+		}
+		if i == 0 {
+			result = posting
+			continue
+		}
+		result = intersectSortedU32(result, posting)
+		if len(result) == 0 {
+			return nil
+		}
+	}
+	return result
+}
+
+func (t *TrigramIndex) verify(candidates []uint32, match func(string) bool) []*SearchResult {
+	var results []*SearchResult
+	for _, idx := range candidates {
+		chunk := t.chunks[idx]
+		if match(chunk.Content) {
+			results = append(results, &SearchResult{Chunk: chunk, Score: 1.0})
+		}
+	}
+	return results
+}
+
+// trigramOp is the kind of node in a boolean query over trigram posting
+// lists, built from a regex's syntax tree.
+type trigramOp int
+
+const (
+	opAll trigramOp = iota // no trigram requirement: matches every chunk
+	opAnd
+	opOr
+	opTrigram
+)
+
+type trigramQuery struct {
+	op      trigramOp
+	trigram string
+	subs    []*trigramQuery
+}
+
+// buildTrigramQuery walks a parsed regexp and extracts the set of trigrams
+// every match must contain: concatenated literal runs contribute AND'd
+// trigrams (including ones spanning the boundary between adjacent literal
+// children), alternations contribute OR, and anything that can match
+// arbitrary text (., character classes, *, ?) contributes no constraint.
+func buildTrigramQuery(re *syntax.Regexp) *trigramQuery {
+	switch re.Op {
+	case syntax.OpLiteral:
+		return literalTrigramQuery(string(re.Rune))
+
+	case syntax.OpConcat:
+		var subs []*trigramQuery
+		var literalBuf []rune
+
+		flush := func() {
+			if len(literalBuf) > 0 {
+				subs = append(subs, literalTrigramQuery(string(literalBuf)))
+				literalBuf = nil
+			}
+		}
+
+		for _, sub := range re.Sub {
+			if sub.Op == syntax.OpLiteral {
+				literalBuf = append(literalBuf, sub.Rune...)
+				continue
+			}
+			flush()
+			subs = append(subs, buildTrigramQuery(sub))
+		}
+		flush()
+
+		return andTrigramQuery(subs)
+
+	case syntax.OpAlternate:
+		subs := make([]*trigramQuery, len(re.Sub))
+		for i, sub := range re.Sub {
+			subs[i] = buildTrigramQuery(sub)
+		}
+		return &trigramQuery{op: opOr, subs: subs}
+
+	case syntax.OpCapture:
+		return buildTrigramQuery(re.Sub[0])
+
+	case syntax.OpPlus:
+		// re+ must match re at least once, so it has the same requirement.
+		return buildTrigramQuery(re.Sub[0])
+
+	case syntax.OpRepeat:
+		if re.Min >= 1 {
+			return buildTrigramQuery(re.Sub[0])
+		}
+		return &trigramQuery{op: opAll}
+
+	default:
+		// OpStar, OpQuest, OpCharClass, OpAnyChar, OpAnyCharNotNL,
+		// OpBeginLine/Text, OpEndLine/Text, OpEmptyMatch, etc. can all match
+		// without any specific trigram being present.
+		return &trigramQuery{op: opAll}
+	}
+}
+
+// literalTrigramQuery returns the AND of every trigram in s, or opAll if s
+// is too short to contain one.
+func literalTrigramQuery(s string) *trigramQuery {
+	trigrams := uniqueTrigrams(s)
+	if len(trigrams) == 0 {
+		return &trigramQuery{op: opAll}
+	}
+
+	subs := make([]*trigramQuery, len(trigrams))
+	for i, tri := range trigrams {
+		subs[i] = &trigramQuery{op: opTrigram, trigram: tri}
+	}
+	return andTrigramQuery(subs)
+}
+
+// andTrigramQuery ANDs subs together, dropping unconstrained (opAll)
+// children since they add no information.
+func andTrigramQuery(subs []*trigramQuery) *trigramQuery {
+	var filtered []*trigramQuery
+	for _, s := range subs {
+		if s.op != opAll {
+			filtered = append(filtered, s)
+		}
+	}
+	switch len(filtered) {
+	case 0:
+		return &trigramQuery{op: opAll}
+	case 1:
+		return filtered[0]
+	default:
+		return &trigramQuery{op: opAnd, subs: filtered}
+	}
+}
+
+// eval evaluates query against the posting lists, returning the matching
+// (and still-sorted) chunk indices. The second return value is false if the
+// query carries no constraint (an opAll somewhere that couldn't be
+// eliminated), meaning every chunk must be treated as a candidate.
+func (t *TrigramIndex) eval(q *trigramQuery) ([]uint32, bool) {
+	switch q.op {
+	case opTrigram:
+		return t.postings[q.trigram], true
+
+	case opAnd:
+		var result []uint32
+		haveResult := false
+		for _, sub := range q.subs {
+			posting, constrained := t.eval(sub)
+			if !constrained {
+				continue
+			}
+			if !haveResult {
+				result = posting
+				haveResult = true
+				continue
+			}
+			result = intersectSortedU32(result, posting)
+		}
+		return result, haveResult
+
+	case opOr:
+		var result []uint32
+		for _, sub := range q.subs {
+			posting, constrained := t.eval(sub)
+			if !constrained {
+				// One unconstrained branch means the whole OR can't be
+				// narrowed: any chunk might match that branch.
+				return nil, false
+			}
+			result = unionSortedU32(result, posting)
+		}
+		return result, true
+
+	default: // opAll
+		return nil, false
+	}
+}
+
+// uniqueTrigrams returns every distinct 3-byte substring of s.
+func uniqueTrigrams(s string) []string {
+	if len(s) < 3 {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	var out []string
+	for i := 0; i+3 <= len(s); i++ {
+		tri := s[i : i+3]
+		if !seen[tri] {
+			seen[tri] = true
+			out = append(out, tri)
+		}
+	}
+	return out
+}
+
+// isLiteralPattern reports whether pattern contains no regex metacharacters,
+// so it can be searched as a plain substring instead of compiled.
+func isLiteralPattern(pattern string) bool {
+	return !strings.ContainsAny(pattern, `.+*?()[]{}|^$\`)
+}
+
+// allIndices returns [0, n) as a candidate list, used when a query has no
+// trigram constraint to narrow the search with.
+func allIndices(n int) []uint32 {
+	out := make([]uint32, n)
+	for i := range out {
+		out[i] = uint32(i)
+	}
+	return out
+}
+
+// intersectSortedU32 returns the sorted intersection of two sorted slices.
+func intersectSortedU32(a, b []uint32) []uint32 {
+	var out []uint32
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			out = append(out, a[i])
+			i++
+			j++
+		case a[i] < b[j]:
+			i++
+		default:
+			j++
+		}
+	}
+	return out
+}
+
+// unionSortedU32 returns the sorted, deduplicated union of two sorted
+// slices.
+func unionSortedU32(a, b []uint32) []uint32 {
+	var out []uint32
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			out = append(out, a[i])
+			i++
+			j++
+		case a[i] < b[j]:
+			out = append(out, a[i])
+			i++
+		default:
+			out = append(out, b[j])
+			j++
+		}
+	}
+	out = append(out, a[i:]...)
+	out = append(out, b[j:]...)
+	return out
+}
@@ -0,0 +1,196 @@
+// Package wal implements a write-ahead log for VectorStore mutations,
+// modeled on the WAL ingesters in large streaming-metrics systems (Prometheus
+// TSDB, M3DB): every Insert/Delete is framed and appended to an on-disk
+// segment before the caller's in-memory state changes, so a crash mid-write
+// loses at most the last unflushed append instead of the whole store.
+package wal
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+)
+
+// Op identifies what a Record represents.
+type Op byte
+
+const (
+	// OpInsert records that ChunkID with the given Payload (a marshaled
+	// embedding.EmbeddedChunk) was inserted or updated.
+	OpInsert Op = 1
+	// OpDelete records that ChunkID was removed. Payload is empty.
+	OpDelete Op = 2
+	// OpCheckpoint marks that the store's state as of this point was
+	// durably written to its checkpoint file, so replay can skip every
+	// record before the last OpCheckpoint instead of replaying from the
+	// beginning of time.
+	OpCheckpoint Op = 3
+)
+
+// Record is one WAL entry.
+type Record struct {
+	Op      Op
+	ChunkID string
+	Payload []byte
+}
+
+// A Writer appends framed records to a single on-disk segment file. Each
+// record is written as:
+//
+//	[4 bytes: total content length, big-endian]
+//	[4 bytes: CRC32(content), big-endian]
+//	[content: 1 byte op, 2 bytes chunk-ID length, chunk-ID bytes, payload bytes]
+//
+// so a reader can validate a record before trusting it and stop cleanly at a
+// truncated tail left by a crash mid-append.
+type Writer struct {
+	path string
+	file *os.File
+	size int64
+}
+
+// OpenWriter opens (creating if necessary) a WAL segment at path for
+// appending, positioned at its current end so size reports the segment's
+// on-disk size so far.
+func OpenWriter(path string) (*Writer, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open WAL segment: %w", err)
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to stat WAL segment: %w", err)
+	}
+
+	return &Writer{path: path, file: f, size: info.Size()}, nil
+}
+
+// Append writes rec to the segment and fsyncs it before returning, so a
+// successful Append means the record has survived a crash.
+func (w *Writer) Append(rec Record) error {
+	content := encodeRecord(rec)
+
+	var header [8]byte
+	binary.BigEndian.PutUint32(header[0:4], uint32(len(content)))
+	binary.BigEndian.PutUint32(header[4:8], crc32.ChecksumIEEE(content))
+
+	if _, err := w.file.Write(header[:]); err != nil {
+		return fmt.Errorf("failed to write WAL record header: %w", err)
+	}
+	if _, err := w.file.Write(content); err != nil {
+		return fmt.Errorf("failed to write WAL record: %w", err)
+	}
+	if err := w.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync WAL segment: %w", err)
+	}
+
+	w.size += int64(len(header) + len(content))
+	return nil
+}
+
+// Size reports the segment's current size in bytes.
+func (w *Writer) Size() int64 {
+	return w.size
+}
+
+// Truncate resets the segment to empty in place, used after a compacting
+// checkpoint has durably written the store's full state elsewhere and the
+// log leading up to it no longer needs to be replayed.
+func (w *Writer) Truncate() error {
+	if err := w.file.Truncate(0); err != nil {
+		return fmt.Errorf("failed to truncate WAL segment: %w", err)
+	}
+	if _, err := w.file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek WAL segment: %w", err)
+	}
+	w.size = 0
+	return nil
+}
+
+// Close closes the underlying segment file.
+func (w *Writer) Close() error {
+	return w.file.Close()
+}
+
+// ReadAll replays every well-formed record in the segment at path, in
+// append order. A missing file returns no records and no error (a fresh
+// store with no WAL history yet). A truncated final record — the signature
+// of a crash mid-append — stops replay at the last complete record instead
+// of erroring, since everything before it is still valid.
+func ReadAll(path string) ([]Record, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open WAL segment: %w", err)
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	var records []Record
+
+	for {
+		var header [8]byte
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			break
+		}
+
+		length := binary.BigEndian.Uint32(header[0:4])
+		wantCRC := binary.BigEndian.Uint32(header[4:8])
+
+		content := make([]byte, length)
+		if _, err := io.ReadFull(r, content); err != nil {
+			break
+		}
+
+		if crc32.ChecksumIEEE(content) != wantCRC {
+			break
+		}
+
+		rec, err := decodeRecord(content)
+		if err != nil {
+			break
+		}
+		records = append(records, rec)
+	}
+
+	return records, nil
+}
+
+// encodeRecord serializes rec into the WAL's record content format: 1 byte
+// op, 2 bytes chunk-ID length, chunk-ID bytes, then the raw payload.
+func encodeRecord(rec Record) []byte {
+	buf := make([]byte, 0, 3+len(rec.ChunkID)+len(rec.Payload))
+	buf = append(buf, byte(rec.Op))
+
+	var idLen [2]byte
+	binary.BigEndian.PutUint16(idLen[:], uint16(len(rec.ChunkID)))
+	buf = append(buf, idLen[:]...)
+	buf = append(buf, rec.ChunkID...)
+	buf = append(buf, rec.Payload...)
+
+	return buf
+}
+
+func decodeRecord(content []byte) (Record, error) {
+	if len(content) < 3 {
+		return Record{}, fmt.Errorf("WAL record too short")
+	}
+
+	op := Op(content[0])
+	idLen := binary.BigEndian.Uint16(content[1:3])
+	if len(content) < 3+int(idLen) {
+		return Record{}, fmt.Errorf("WAL record truncated")
+	}
+
+	chunkID := string(content[3 : 3+idLen])
+	payload := content[3+idLen:]
+
+	return Record{Op: op, ChunkID: chunkID, Payload: payload}, nil
+}
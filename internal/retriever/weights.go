@@ -0,0 +1,63 @@
+package retriever
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// TunedWeights is a (vectorWeight, bm25Weight, RRF k) triple AutoTuneWeights
+// selected by grid search, plus the mean nDCG@topK it scored against the
+// labeled queries that produced it.
+type TunedWeights struct {
+	VectorWeight float32 `json:"vector_weight"`
+	BM25Weight   float32 `json:"bm25_weight"`
+	RRFK         float64 `json:"rrf_k"`
+	NDCG         float64 `json:"ndcg"`
+}
+
+// tunedWeightsPath returns the on-disk path tuned weights for a collection
+// are persisted to, the same .codementor/<name>_<collection>.json
+// convention checkpoint.PathFor and the embedding cache use.
+func tunedWeightsPath(collection string) string {
+	return filepath.Join(".codementor", fmt.Sprintf("retrieval_weights_%s.json", collection))
+}
+
+// LoadTunedWeights reads a collection's previously-persisted TunedWeights,
+// so a process started after a prior AutoTuneWeights run reuses them
+// instead of falling back to the default 0.7/0.3 weights. The second return
+// value is false if nothing has been tuned for this collection yet.
+func LoadTunedWeights(collection string) (TunedWeights, bool) {
+	data, err := os.ReadFile(tunedWeightsPath(collection))
+	if err != nil {
+		return TunedWeights{}, false
+	}
+
+	var w TunedWeights
+	if err := json.Unmarshal(data, &w); err != nil {
+		return TunedWeights{}, false
+	}
+
+	return w, true
+}
+
+// save persists w for collection, atomically (temp file plus rename), the
+// same pattern checkpoint.State.save uses.
+func (w TunedWeights) save(collection string) error {
+	data, err := json.Marshal(w)
+	if err != nil {
+		return fmt.Errorf("failed to encode tuned weights: %w", err)
+	}
+
+	if err := os.MkdirAll(".codementor", 0755); err != nil {
+		return fmt.Errorf("failed to create .codementor directory: %w", err)
+	}
+
+	path := tunedWeightsPath(collection)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write tuned weights: %w", err)
+	}
+	return os.Rename(tmp, path)
+}